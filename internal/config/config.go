@@ -58,6 +58,7 @@ type Source struct {
 	Name     string            `yaml:"-" json:"-"`               // Name is the name of the source entry
 	Type     string            `yaml:"type" json:"type"`         // Type is the method used to query the source
 	Args     map[string]string `yaml:"args" json:"args"`         // Args provide additional options used by sources
+	CacheTTL string            `yaml:"cacheTTL" json:"cacheTTL"` // CacheTTL overrides the time to keep results in the persistent cache, e.g. "24h", or "0" to disable
 	Key      string            `yaml:"key" json:"key"`           // Deprecated: Key is a unique value to store in a lock file
 	Filter   Filter            `yaml:"filter" json:"filter"`     // Deprecated: Filter specifies which items to include from the source
 	Sort     Sort              `yaml:"sort" json:"sort"`         // Deprecated: Sort is used to pick from multiple results
@@ -74,7 +75,7 @@ type Filter struct {
 // Sort defines how multiple results should be filtered and sorted.
 // By default, sort returns the 0 offset of a descending sort.
 type Sort struct {
-	Method   string `yaml:"method" json:"method"`     // Sorting methods include: semver, numeric, or unset for an ascii sort
+	Method   string `yaml:"method" json:"method"`     // Sorting methods include: semver, pep440, numeric, or unset for an ascii sort
 	Asc      bool   `yaml:"asc" json:"asc"`           // Sort values ascending (smallest number first)
 	Offset   int    `yaml:"offset" json:"offset"`     // Offset within the sorted values to pick
 	Template string `yaml:"template" json:"template"` // Preprocess value to be sorted, this does not effect the resulting version number only the sorting
@@ -211,15 +212,17 @@ func (s Scan) Equal(s2 Scan) bool {
 
 func (s Source) Clone() Source {
 	return Source{
-		Name: s.Name,
-		Type: s.Type,
-		Args: maps.Clone(s.Args),
+		Name:     s.Name,
+		Type:     s.Type,
+		Args:     maps.Clone(s.Args),
+		CacheTTL: s.CacheTTL,
 	}
 }
 
 func (s Source) Equal(s2 Source) bool {
 	if s.Name != s2.Name ||
 		s.Type != s2.Type ||
+		s.CacheTTL != s2.CacheTTL ||
 		!eqStrMaps(s.Args, s2.Args) {
 		return false
 	}
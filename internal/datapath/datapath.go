@@ -0,0 +1,301 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package datapath selects values from structured documents (JSON and YAML) using a path expression.
+//
+// Two syntaxes are supported:
+//   - JSONPath style: "$.spec.containers[*].image", "spec.containers[0]['image']", or "$..image".
+//     The leading "$" is optional, "*" matches any key or index, and ".." matches any number of levels.
+//   - JSON Pointer: "/spec/containers/0/image", where a numeric entry also matches an array index.
+package datapath
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Step is one entry in the location of a value, either a key in a map or an index in an array.
+type Step struct {
+	Key     string // key within a map
+	Index   int    // index within an array
+	IsIndex bool   // true when the step is an array index
+}
+
+// KeyStep returns a step for a key in a map.
+func KeyStep(key string) Step {
+	return Step{Key: key}
+}
+
+// IndexStep returns a step for an index in an array.
+func IndexStep(i int) Step {
+	return Step{Index: i, IsIndex: true}
+}
+
+// Steps is the location of a value within a document.
+type Steps []Step
+
+var plainKeyRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// String outputs the location using the JSONPath syntax.
+func (s Steps) String() string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, step := range s {
+		switch {
+		case step.IsIndex:
+			fmt.Fprintf(&sb, "[%d]", step.Index)
+		case plainKeyRE.MatchString(step.Key):
+			sb.WriteString("." + step.Key)
+		default:
+			sb.WriteString("['" + strings.ReplaceAll(step.Key, "'", `\'`) + "']")
+		}
+	}
+	return sb.String()
+}
+
+// Append returns a new list of steps, without modifying the original slice.
+func (s Steps) Append(step Step) Steps {
+	out := make(Steps, len(s), len(s)+1)
+	copy(out, s)
+	return append(out, step)
+}
+
+type segKind int
+
+const (
+	segKey       segKind = iota // match a specific key
+	segIndex                    // match a specific index
+	segWildcard                 // match any key or index
+	segRecursive                // match zero or more steps
+)
+
+type segment struct {
+	kind  segKind
+	key   string
+	index int
+}
+
+// Path is a parsed path expression.
+type Path struct {
+	expr string
+	segs []segment
+}
+
+// Parse converts a path expression into a [Path].
+func Parse(expr string) (*Path, error) {
+	p := &Path{expr: expr}
+	var err error
+	if strings.HasPrefix(expr, "/") {
+		p.segs = parsePointer(expr)
+	} else {
+		p.segs, err = parseJSONPath(expr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse path %s: %w", expr, err)
+	}
+	return p, nil
+}
+
+// String returns the original expression.
+func (p *Path) String() string {
+	return p.expr
+}
+
+// Match returns true when the location of a value matches the path.
+func (p *Path) Match(steps Steps) bool {
+	return matchSegs(p.segs, steps)
+}
+
+// Exact returns true when the path has no wildcard or recursive segments, selecting at most one value.
+func (p *Path) Exact() bool {
+	for _, seg := range p.segs {
+		if seg.kind == segWildcard || seg.kind == segRecursive {
+			return false
+		}
+	}
+	return true
+}
+
+// Value is a value found in a decoded document.
+type Value struct {
+	Steps  Steps // location of the value
+	Value  any   // the matching value
+	Parent any   // the map or slice containing the value, nil for the root
+}
+
+// Find returns the values matching the path from a document decoded into maps and slices, e.g. by encoding/json.
+// Map keys are visited in sorted order.
+func (p *Path) Find(doc any) []Value {
+	found := []Value{}
+	p.find(doc, nil, Steps{}, &found)
+	return found
+}
+
+func (p *Path) find(v, parent any, steps Steps, found *[]Value) {
+	if p.Match(steps) {
+		*found = append(*found, Value{Steps: steps, Value: v, Parent: parent})
+	}
+	switch vt := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(vt))
+		for k := range vt {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			p.find(vt[k], v, steps.Append(KeyStep(k)), found)
+		}
+	case map[any]any:
+		keys := make([]string, 0, len(vt))
+		orig := map[string]any{}
+		for k := range vt {
+			ks := fmt.Sprint(k)
+			keys = append(keys, ks)
+			orig[ks] = k
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			p.find(vt[orig[k]], v, steps.Append(KeyStep(k)), found)
+		}
+	case []any:
+		for i, item := range vt {
+			p.find(item, v, steps.Append(IndexStep(i)), found)
+		}
+	}
+}
+
+func matchSegs(segs []segment, steps Steps) bool {
+	if len(segs) == 0 {
+		return len(steps) == 0
+	}
+	if segs[0].kind == segRecursive {
+		// try every possible number of skipped steps
+		for i := 0; i <= len(steps); i++ {
+			if matchSegs(segs[1:], steps[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(steps) == 0 || !segs[0].match(steps[0]) {
+		return false
+	}
+	return matchSegs(segs[1:], steps[1:])
+}
+
+func (seg segment) match(step Step) bool {
+	switch seg.kind {
+	case segWildcard:
+		return true
+	case segIndex:
+		return step.IsIndex && step.Index == seg.index
+	case segKey:
+		if step.IsIndex {
+			// numeric keys, used by JSON Pointer, may also refer to an array index
+			return seg.key == strconv.Itoa(step.Index)
+		}
+		return step.Key == seg.key
+	}
+	return false
+}
+
+func parsePointer(expr string) []segment {
+	segs := []segment{}
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	for _, part := range strings.Split(expr, "/")[1:] {
+		segs = append(segs, segment{kind: segKey, key: unescape.Replace(part)})
+	}
+	return segs
+}
+
+func parseJSONPath(expr string) ([]segment, error) {
+	segs := []segment{}
+	i := 0
+	if strings.HasPrefix(expr, "$") {
+		i = 1
+	} else if expr != "" && expr[0] != '.' && expr[0] != '[' {
+		// allow the leading "$." to be skipped
+		expr = "." + expr
+	}
+	for i < len(expr) {
+		switch expr[i] {
+		case '.':
+			i++
+			if i < len(expr) && expr[i] == '.' {
+				segs = append(segs, segment{kind: segRecursive})
+				i++
+			}
+			if i < len(expr) && expr[i] == '[' {
+				continue
+			}
+			end := i
+			for end < len(expr) && expr[end] != '.' && expr[end] != '[' {
+				end++
+			}
+			name := expr[i:end]
+			if name == "" {
+				return nil, fmt.Errorf("empty key at offset %d", i)
+			}
+			if name == "*" {
+				segs = append(segs, segment{kind: segWildcard})
+			} else {
+				segs = append(segs, segment{kind: segKey, key: name})
+			}
+			i = end
+		case '[':
+			end := strings.IndexByte(expr[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing closing bracket at offset %d", i)
+			}
+			inner := expr[i+1 : i+end]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') {
+				// quoted keys may contain a closing bracket, search for the closing quote
+				q := inner[0]
+				j := i + 2
+				var key strings.Builder
+				for ; j < len(expr) && expr[j] != q; j++ {
+					if expr[j] == '\\' && j+1 < len(expr) {
+						j++
+					}
+					key.WriteByte(expr[j])
+				}
+				if j+1 >= len(expr) || expr[j+1] != ']' {
+					return nil, fmt.Errorf("invalid quoted key at offset %d", i)
+				}
+				segs = append(segs, segment{kind: segKey, key: key.String()})
+				i = j + 2
+				continue
+			}
+			if inner == "*" {
+				segs = append(segs, segment{kind: segWildcard})
+			} else {
+				idx, err := strconv.Atoi(inner)
+				if err != nil || idx < 0 {
+					return nil, fmt.Errorf("invalid index %q at offset %d", inner, i)
+				}
+				segs = append(segs, segment{kind: segIndex, index: idx})
+			}
+			i += end + 1
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", expr[i], i)
+		}
+	}
+	if len(segs) > 0 && segs[len(segs)-1].kind == segRecursive {
+		return nil, fmt.Errorf("recursive descent must be followed by a key")
+	}
+	return segs, nil
+}
@@ -0,0 +1,203 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datapath
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	containerImage := Steps{KeyStep("spec"), KeyStep("containers"), IndexStep(1), KeyStep("image")}
+	tt := []struct {
+		name     string
+		expr     string
+		steps    Steps
+		expect   bool
+		parseErr bool
+	}{
+		{
+			name:   "root",
+			expr:   "$",
+			steps:  Steps{},
+			expect: true,
+		},
+		{
+			name:   "keys",
+			expr:   "$.spec.containers[1].image",
+			steps:  containerImage,
+			expect: true,
+		},
+		{
+			name:   "no leading dollar",
+			expr:   "spec.containers[1].image",
+			steps:  containerImage,
+			expect: true,
+		},
+		{
+			name:   "wrong index",
+			expr:   "$.spec.containers[0].image",
+			steps:  containerImage,
+			expect: false,
+		},
+		{
+			name:   "wildcard index",
+			expr:   "$.spec.containers[*].image",
+			steps:  containerImage,
+			expect: true,
+		},
+		{
+			name:   "wildcard key",
+			expr:   "$.spec.*[1].image",
+			steps:  containerImage,
+			expect: true,
+		},
+		{
+			name:   "too short",
+			expr:   "$.spec.containers",
+			steps:  containerImage,
+			expect: false,
+		},
+		{
+			name:   "recursive",
+			expr:   "$..image",
+			steps:  containerImage,
+			expect: true,
+		},
+		{
+			name:   "recursive mismatch",
+			expr:   "$..name",
+			steps:  containerImage,
+			expect: false,
+		},
+		{
+			name:   "quoted key",
+			expr:   `$['devDependencies']["@scope/pkg.name"]`,
+			steps:  Steps{KeyStep("devDependencies"), KeyStep("@scope/pkg.name")},
+			expect: true,
+		},
+		{
+			name:   "pointer",
+			expr:   "/spec/containers/1/image",
+			steps:  containerImage,
+			expect: true,
+		},
+		{
+			name:   "pointer escape",
+			expr:   "/dependencies/@scope~1pkg",
+			steps:  Steps{KeyStep("dependencies"), KeyStep("@scope/pkg")},
+			expect: true,
+		},
+		{
+			name:     "missing bracket",
+			expr:     "$.spec[0",
+			parseErr: true,
+		},
+		{
+			name:     "invalid index",
+			expr:     "$.spec[x]",
+			parseErr: true,
+		},
+		{
+			name:     "trailing recursive",
+			expr:     "$.spec..",
+			parseErr: true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := Parse(tc.expr)
+			if tc.parseErr {
+				if err == nil {
+					t.Errorf("parse did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if p.Match(tc.steps) != tc.expect {
+				t.Errorf("unexpected match result for %s on %s, expected %t", tc.expr, tc.steps, tc.expect)
+			}
+		})
+	}
+}
+
+func TestExact(t *testing.T) {
+	tt := []struct {
+		expr   string
+		expect bool
+	}{
+		{expr: "$", expect: true},
+		{expr: "$.spec.containers[1].image", expect: true},
+		{expr: "/spec/containers/1/image", expect: true},
+		{expr: "$.spec.containers[*].image", expect: false},
+		{expr: "$..image", expect: false},
+		{expr: "$.spec.*", expect: false},
+	}
+	for _, tc := range tt {
+		t.Run(tc.expr, func(t *testing.T) {
+			p, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if p.Exact() != tc.expect {
+				t.Errorf("unexpected exact result for %s, expected %t", tc.expr, tc.expect)
+			}
+		})
+	}
+}
+
+func TestStepsString(t *testing.T) {
+	steps := Steps{KeyStep("spec"), IndexStep(0), KeyStep("app.kubernetes.io/name")}
+	expect := "$.spec[0]['app.kubernetes.io/name']"
+	if steps.String() != expect {
+		t.Errorf("unexpected string, expected %s, received %s", expect, steps.String())
+	}
+	// output can be parsed to match the same steps
+	p, err := Parse(steps.String())
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if !p.Match(steps) {
+		t.Errorf("parsed path %s does not match", steps.String())
+	}
+}
+
+func TestFind(t *testing.T) {
+	var doc any
+	err := json.Unmarshal([]byte(`{"releases": [{"version": "1.0", "url": "a"}, {"version": "2.0", "url": "b"}], "latest": {"version": "2.0"}}`), &doc)
+	if err != nil {
+		t.Fatalf("failed to parse json: %v", err)
+	}
+	p, err := Parse("$..version")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	found := p.Find(doc)
+	expect := []string{"$.latest.version", "$.releases[0].version", "$.releases[1].version"}
+	if len(found) != len(expect) {
+		t.Fatalf("unexpected number of values, expected %d, received %d", len(expect), len(found))
+	}
+	for i, f := range found {
+		if f.Steps.String() != expect[i] {
+			t.Errorf("unexpected location, expected %s, received %s", expect[i], f.Steps.String())
+		}
+		parent, ok := f.Parent.(map[string]any)
+		if !ok || parent["version"] != f.Value {
+			t.Errorf("unexpected parent for %s: %v", f.Steps.String(), f.Parent)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff generates unified diffs between two versions of a file
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Context is the number of unchanged lines included around each change.
+const Context = 3
+
+const noNewline = "\\ No newline at end of file\n"
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is a single line in the edit script, aLine and bLine are the 0 based line numbers in each file.
+type op struct {
+	kind  opKind
+	aLine int
+	bLine int
+}
+
+// Unified returns a unified diff from a to b, using nameA and nameB in the headers.
+// Nil is returned when the content is identical.
+func Unified(nameA, nameB string, a, b []byte) []byte {
+	if bytes.Equal(a, b) {
+		return nil
+	}
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+	ops := editScript(aLines, bLines)
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", nameA, nameB)
+	for start := 0; start < len(ops); {
+		// find the next change
+		for start < len(ops) && ops[start].kind == opEqual {
+			start++
+		}
+		if start >= len(ops) {
+			break
+		}
+		// extend the hunk until the gap between changes exceeds the context on both sides
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != opEqual {
+				end = i
+			} else if i-end > Context*2 {
+				break
+			}
+		}
+		hunkStart := max(start-Context, 0)
+		hunkEnd := min(end+Context+1, len(ops))
+		writeHunk(buf, ops[hunkStart:hunkEnd], aLines, bLines)
+		start = hunkEnd
+	}
+	return buf.Bytes()
+}
+
+// writeHunk outputs a single hunk with the header.
+func writeHunk(buf *bytes.Buffer, ops []op, aLines, bLines []string) {
+	aCount, bCount := 0, 0
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			aCount++
+			bCount++
+		case opDelete:
+			aCount++
+		case opInsert:
+			bCount++
+		}
+	}
+	// the start of each range is the first line of that file in the hunk, or the preceding line for an empty range
+	aStart, bStart := ops[0].aLine+1, ops[0].bLine+1
+	if aCount == 0 {
+		aStart--
+	}
+	if bCount == 0 {
+		bStart--
+	}
+	fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			writeLine(buf, " ", aLines[o.aLine])
+		case opDelete:
+			writeLine(buf, "-", aLines[o.aLine])
+		case opInsert:
+			writeLine(buf, "+", bLines[o.bLine])
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func writeLine(buf *bytes.Buffer, prefix, line string) {
+	buf.WriteString(prefix)
+	buf.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		buf.WriteString("\n" + noNewline)
+	}
+}
+
+// splitLines splits content into lines, each including the trailing newline when one exists.
+func splitLines(b []byte) []string {
+	lines := []string{}
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			lines = append(lines, string(b))
+			break
+		}
+		lines = append(lines, string(b[:i+1]))
+		b = b[i+1:]
+	}
+	return lines
+}
+
+// editScript returns the shortest list of operations to convert a to b using the Myers diff algorithm.
+// Each op tracks the position in both files, so deletes include the next line of b, and inserts the next line of a.
+func editScript(a, b []string) []op {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	trace := [][]int{}
+	found := false
+	for d := 0; d <= n+m && !found; d++ {
+		trace = append(trace, append([]int{}, v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	// backtrack through the trace to build the list of operations in reverse
+	ops := []op{}
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && vd[offset+k-1] < vd[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := vd[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: opEqual, aLine: x, bLine: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, op{kind: opInsert, aLine: prevX, bLine: prevY})
+			} else {
+				ops = append(ops, op{kind: opDelete, aLine: prevX, bLine: prevY})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
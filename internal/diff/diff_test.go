@@ -0,0 +1,146 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tt := []struct {
+		name   string
+		a, b   string
+		expect string
+	}{
+		{
+			name:   "identical",
+			a:      "a\nb\nc\n",
+			b:      "a\nb\nc\n",
+			expect: "",
+		},
+		{
+			name: "single line",
+			a:    "ver=1\n",
+			b:    "ver=2\n",
+			expect: `--- a/file
++++ b/file
+@@ -1 +1 @@
+-ver=1
++ver=2
+`,
+		},
+		{
+			name: "context",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			expect: `--- a/file
++++ b/file
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`,
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			expect: `--- a/file
++++ b/file
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++twelve
+`,
+		},
+		{
+			name: "merged hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\neight\n",
+			expect: `--- a/file
++++ b/file
+@@ -1,8 +1,8 @@
+-1
++one
+ 2
+ 3
+ 4
+ 5
+ 6
+ 7
+-8
++eight
+`,
+		},
+		{
+			name: "insert and delete",
+			a:    "a\nb\nc\n",
+			b:    "a\nc\nd\n",
+			expect: `--- a/file
++++ b/file
+@@ -1,3 +1,3 @@
+ a
+-b
+ c
++d
+`,
+		},
+		{
+			name: "new file",
+			a:    "",
+			b:    "a\n",
+			expect: `--- a/file
++++ b/file
+@@ -0,0 +1 @@
++a
+`,
+		},
+		{
+			name: "no newline",
+			a:    "a\nver=1",
+			b:    "a\nver=2",
+			expect: `--- a/file
++++ b/file
+@@ -1,2 +1,2 @@
+ a
+-ver=1
+\ No newline at end of file
++ver=2
+\ No newline at end of file
+`,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			out := Unified("a/file", "b/file", []byte(tc.a), []byte(tc.b))
+			if string(out) != tc.expect {
+				t.Errorf("unexpected diff, expected:\n%s\nreceived:\n%s", tc.expect, out)
+			}
+		})
+	}
+}
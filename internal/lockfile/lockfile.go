@@ -26,12 +26,16 @@ import (
 	"sync"
 )
 
+// ErrNotFound is returned when a lock entry does not exist.
+var ErrNotFound = errors.New("not found")
+
 // Lock stores known versions from a scan or source
 type Lock struct {
 	Name    string `json:"name"`    // name for a group of locks, e.g. git versions
 	Key     string `json:"key"`     // key for a specific lock, e.g. repo and branch
 	Version string `json:"version"` // version of the lock, e.g. commit hash
 	used    bool   // tracks if a lock was used
+	current bool   // tracks if a lock was set in the current run, rather than loaded from the file
 }
 
 type Locks struct {
@@ -53,11 +57,26 @@ func (l *Locks) Get(name, key string) (*Lock, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	if _, ok := l.Lock[name]; !ok {
-		return nil, fmt.Errorf("not found")
+		return nil, ErrNotFound
 	}
 	entry, ok := l.Lock[name][key]
 	if !ok {
-		return nil, fmt.Errorf("not found")
+		return nil, ErrNotFound
+	}
+	entry.used = true
+	return entry, nil
+}
+
+// GetCurrent returns a lock that was set in the current run, ignoring entries loaded from the lock file.
+func (l *Locks) GetCurrent(name, key string) (*Lock, error) {
+	if l == nil || l.Lock == nil {
+		return nil, fmt.Errorf("cannot Get from a nil pointer")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.Lock[name][key]
+	if !ok || !entry.current {
+		return nil, ErrNotFound
 	}
 	entry.used = true
 	return entry, nil
@@ -77,10 +96,32 @@ func (l *Locks) Set(name, key, version string) error {
 		Key:     key,
 		Version: version,
 		used:    true,
+		current: true,
 	}
 	return nil
 }
 
+// Clone returns a copy of the locks, changes to the copy do not modify the original.
+func (l *Locks) Clone() *Locks {
+	if l == nil || l.Lock == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := &Locks{
+		Filename: l.Filename,
+		Lock:     make(map[string]map[string]*Lock, len(l.Lock)),
+	}
+	for name, keys := range l.Lock {
+		c.Lock[name] = make(map[string]*Lock, len(keys))
+		for key, entry := range keys {
+			entryCopy := *entry
+			c.Lock[name][key] = &entryCopy
+		}
+	}
+	return c
+}
+
 func LoadReader(rdr io.Reader) (*Locks, error) {
 	decode := json.NewDecoder(rdr)
 	l := New()
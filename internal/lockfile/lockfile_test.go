@@ -16,6 +16,7 @@ package lockfile
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"testing"
 )
@@ -104,6 +105,51 @@ func TestLockfile(t *testing.T) {
 	}
 }
 
+func TestClone(t *testing.T) {
+	l := New()
+	if err := l.Set("Test", "X", "123"); err != nil {
+		t.Fatalf("failed to set X: %v", err)
+	}
+	c := l.Clone()
+	if err := c.Set("Test", "X", "456"); err != nil {
+		t.Fatalf("failed to set X: %v", err)
+	}
+	if err := c.Set("Test", "Y", "789"); err != nil {
+		t.Fatalf("failed to set Y: %v", err)
+	}
+	entry, err := l.Get("Test", "X")
+	if err != nil || entry.Version != "123" {
+		t.Errorf("original was modified: %v, %v", entry, err)
+	}
+	if _, err := l.Get("Test", "Y"); err == nil {
+		t.Errorf("original includes an entry added to the clone")
+	}
+	entry, err = c.Get("Test", "X")
+	if err != nil || entry.Version != "456" {
+		t.Errorf("unexpected clone entry: %v, %v", entry, err)
+	}
+}
+
+func TestGetCurrent(t *testing.T) {
+	l, err := LoadReader(bytes.NewBufferString(`{"name":"Test","key":"X","version":"123"}` + "\n"))
+	if err != nil {
+		t.Fatalf("failed to load: %v", err)
+	}
+	if _, err := l.GetCurrent("Test", "X"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("loaded entry returned as current: %v", err)
+	}
+	if _, err := l.GetCurrent("Missing", "X"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing entry did not return not found: %v", err)
+	}
+	if err := l.Set("Test", "X", "456"); err != nil {
+		t.Fatalf("failed to set X: %v", err)
+	}
+	entry, err := l.Clone().GetCurrent("Test", "X")
+	if err != nil || entry.Version != "456" {
+		t.Errorf("unexpected current entry: %v, %v", entry, err)
+	}
+}
+
 func TestNil(t *testing.T) {
 	var l *Locks
 	err := l.Set("A", "B", "C")
@@ -114,6 +160,10 @@ func TestNil(t *testing.T) {
 	if err == nil {
 		t.Errorf("Get succeeded")
 	}
+	_, err = l.GetCurrent("A", "B")
+	if err == nil {
+		t.Errorf("GetCurrent succeeded")
+	}
 	err = l.Save(false)
 	if err == nil {
 		t.Errorf("Save succeeded")
@@ -122,6 +172,9 @@ func TestNil(t *testing.T) {
 	if err == nil {
 		t.Errorf("SaveFile succeeded")
 	}
+	if l.Clone() != nil {
+		t.Errorf("Clone returned a value")
+	}
 	b := bytes.NewBuffer([]byte{})
 	err = l.SaveWriter(b, false)
 	if err == nil {
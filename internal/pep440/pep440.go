@@ -0,0 +1,254 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pep440 parses and orders Python package versions following PEP 440.
+//
+// Versions have the form "[N!]N(.N)*[{a|b|rc}N][.postN][.devN][+local]",
+// and the alternate spellings allowed by the spec (e.g. "1.0-alpha.1", "1.0-1", "v1.0") are normalized.
+package pep440
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var versionRE = regexp.MustCompile(`^(?i)v?` +
+	`(?:([0-9]+)!)?` + // epoch
+	`([0-9]+(?:\.[0-9]+)*)` + // release
+	`(?:[-_.]?(alpha|beta|preview|pre|a|b|c|rc)[-_.]?([0-9]+)?)?` + // pre-release
+	`(?:-([0-9]+)|[-_.]?(post|rev|r)[-_.]?([0-9]+)?)?` + // post-release
+	`(?:[-_.]?(dev)[-_.]?([0-9]+)?)?` + // dev release
+	`(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`) // local version
+
+// Version is a parsed PEP 440 version.
+type Version struct {
+	Epoch    int
+	Release  []int
+	PreLabel string // PreLabel is one of "a", "b", or "rc", empty when there is no pre-release
+	PreNum   int
+	Post     int // Post is -1 when there is no post-release
+	Dev      int // Dev is -1 when there is no dev release
+	Local    []string
+	original string
+}
+
+// Parse returns the version for a string, or an error when the string is not a valid PEP 440 version.
+func Parse(s string) (*Version, error) {
+	m := versionRE.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, fmt.Errorf("invalid PEP 440 version: %s", s)
+	}
+	v := &Version{Post: -1, Dev: -1, original: s}
+	var err error
+	if m[1] != "" {
+		if v.Epoch, err = strconv.Atoi(m[1]); err != nil {
+			return nil, fmt.Errorf("invalid epoch in %s: %w", s, err)
+		}
+	}
+	for _, part := range strings.Split(m[2], ".") {
+		i, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid release in %s: %w", s, err)
+		}
+		v.Release = append(v.Release, i)
+	}
+	if m[3] != "" {
+		switch strings.ToLower(m[3]) {
+		case "a", "alpha":
+			v.PreLabel = "a"
+		case "b", "beta":
+			v.PreLabel = "b"
+		default:
+			v.PreLabel = "rc"
+		}
+		if v.PreNum, err = atoiOpt(m[4]); err != nil {
+			return nil, fmt.Errorf("invalid pre-release in %s: %w", s, err)
+		}
+	}
+	if m[5] != "" {
+		if v.Post, err = strconv.Atoi(m[5]); err != nil {
+			return nil, fmt.Errorf("invalid post-release in %s: %w", s, err)
+		}
+	} else if m[6] != "" {
+		if v.Post, err = atoiOpt(m[7]); err != nil {
+			return nil, fmt.Errorf("invalid post-release in %s: %w", s, err)
+		}
+	}
+	if m[8] != "" {
+		if v.Dev, err = atoiOpt(m[9]); err != nil {
+			return nil, fmt.Errorf("invalid dev release in %s: %w", s, err)
+		}
+	}
+	if m[10] != "" {
+		v.Local = strings.FieldsFunc(strings.ToLower(m[10]), func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		})
+	}
+	return v, nil
+}
+
+// atoiOpt parses an optional number, where an empty string is 0.
+func atoiOpt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// Original returns the string that was parsed.
+func (v *Version) Original() string {
+	return v.original
+}
+
+// String returns the normalized version.
+func (v *Version) String() string {
+	var sb strings.Builder
+	if v.Epoch != 0 {
+		fmt.Fprintf(&sb, "%d!", v.Epoch)
+	}
+	for i, r := range v.Release {
+		if i > 0 {
+			sb.WriteString(".")
+		}
+		sb.WriteString(strconv.Itoa(r))
+	}
+	if v.PreLabel != "" {
+		fmt.Fprintf(&sb, "%s%d", v.PreLabel, v.PreNum)
+	}
+	if v.Post >= 0 {
+		fmt.Fprintf(&sb, ".post%d", v.Post)
+	}
+	if v.Dev >= 0 {
+		fmt.Fprintf(&sb, ".dev%d", v.Dev)
+	}
+	if len(v.Local) > 0 {
+		sb.WriteString("+" + strings.Join(v.Local, "."))
+	}
+	return sb.String()
+}
+
+// IsPrerelease is true for pre-releases and dev releases.
+func (v *Version) IsPrerelease() bool {
+	return v.PreLabel != "" || v.Dev >= 0
+}
+
+// Compare returns -1, 0, or 1 when v is less than, equal to, or greater than o.
+func (v *Version) Compare(o *Version) int {
+	if c := cmpInt(v.Epoch, o.Epoch); c != 0 {
+		return c
+	}
+	// trailing zeros are ignored, 1.0 == 1.0.0
+	for i := 0; i < max(len(v.Release), len(o.Release)); i++ {
+		a, b := 0, 0
+		if i < len(v.Release) {
+			a = v.Release[i]
+		}
+		if i < len(o.Release) {
+			b = o.Release[i]
+		}
+		if c := cmpInt(a, b); c != 0 {
+			return c
+		}
+	}
+	if c := cmpInt(v.preRank(), o.preRank()); c != 0 {
+		return c
+	}
+	if v.PreLabel != "" {
+		if c := cmpInt(v.PreNum, o.PreNum); c != 0 {
+			return c
+		}
+	}
+	// no post-release (-1) sorts before any post-release
+	if c := cmpInt(v.Post, o.Post); c != 0 {
+		return c
+	}
+	// no dev release sorts after any dev release
+	if c := cmpInt(devRank(v.Dev), devRank(o.Dev)); c != 0 {
+		return c
+	}
+	return cmpLocal(v.Local, o.Local)
+}
+
+// preRank orders the pre-release labels, a dev release of the final version sorts before any pre-release.
+func (v *Version) preRank() int {
+	switch v.PreLabel {
+	case "a":
+		return 1
+	case "b":
+		return 2
+	case "rc":
+		return 3
+	}
+	if v.Post < 0 && v.Dev >= 0 {
+		return 0
+	}
+	return 4
+}
+
+func devRank(dev int) int {
+	if dev < 0 {
+		return int(^uint(0) >> 1)
+	}
+	return dev
+}
+
+// cmpLocal compares local version segments, numeric segments sort after alphanumeric segments.
+func cmpLocal(a, b []string) int {
+	for i := 0; i < min(len(a), len(b)); i++ {
+		ai, aErr := strconv.Atoi(a[i])
+		bi, bErr := strconv.Atoi(b[i])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmpInt(ai, bi)
+		case aErr == nil:
+			c = 1
+		case bErr == nil:
+			c = -1
+		default:
+			c = strings.Compare(a[i], b[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmpInt(len(a), len(b))
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Collection is a list of versions that implements [sort.Interface].
+type Collection []*Version
+
+func (c Collection) Len() int {
+	return len(c)
+}
+
+func (c Collection) Less(i, j int) bool {
+	return c[i].Compare(c[j]) < 0
+}
+
+func (c Collection) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
@@ -0,0 +1,129 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pep440
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tt := []struct {
+		in        string
+		expect    string
+		expectErr bool
+		expectPre bool
+	}{
+		{in: "1.0", expect: "1.0"},
+		{in: "v1.2.3", expect: "1.2.3"},
+		{in: "2!1.0", expect: "2!1.0"},
+		{in: "1.0RC1", expect: "1.0rc1", expectPre: true},
+		{in: "1.0-alpha.2", expect: "1.0a2", expectPre: true},
+		{in: "1.0c1", expect: "1.0rc1", expectPre: true},
+		{in: "1.0b", expect: "1.0b0", expectPre: true},
+		{in: "1.0-1", expect: "1.0.post1"},
+		{in: "1.0.rev2", expect: "1.0.post2"},
+		{in: "1.0post", expect: "1.0.post0"},
+		{in: "1.0.dev", expect: "1.0.dev0", expectPre: true},
+		{in: "1.0a1.post2.dev3", expect: "1.0a1.post2.dev3", expectPre: true},
+		{in: "1.0+Ubuntu-1_2", expect: "1.0+ubuntu.1.2"},
+		{in: " 1.0 ", expect: "1.0"},
+		{in: "", expectErr: true},
+		{in: "latest", expectErr: true},
+		{in: "1.0+", expectErr: true},
+		{in: "1.0.x", expectErr: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.in, func(t *testing.T) {
+			v, err := Parse(tc.in)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("did not fail, parsed %s", v.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if v.String() != tc.expect {
+				t.Errorf("unexpected version, expected %s, received %s", tc.expect, v.String())
+			}
+			if v.Original() != tc.in {
+				t.Errorf("unexpected original, expected %s, received %s", tc.in, v.Original())
+			}
+			if v.IsPrerelease() != tc.expectPre {
+				t.Errorf("unexpected prerelease, expected %t, received %t", tc.expectPre, v.IsPrerelease())
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// ordering from the examples in PEP 440
+	ordered := []string{
+		"1.dev0",
+		"1.0.dev456",
+		"1.0a1",
+		"1.0a2.dev456",
+		"1.0a12.dev456",
+		"1.0a12",
+		"1.0b1.dev456",
+		"1.0b2",
+		"1.0b2.post345.dev456",
+		"1.0b2.post345",
+		"1.0rc1.dev456",
+		"1.0rc1",
+		"1.0",
+		"1.0+abc.5",
+		"1.0+abc.7",
+		"1.0+5",
+		"1.0.post456.dev34",
+		"1.0.post456",
+		"1.0.15",
+		"1.1.dev1",
+		"2.0",
+		"1!0.1",
+	}
+	vers := make(Collection, len(ordered))
+	for i, s := range ordered {
+		v, err := Parse(s)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", s, err)
+		}
+		vers[i] = v
+	}
+	for i := range vers {
+		for j := range vers {
+			expect := cmpInt(i, j)
+			if c := vers[i].Compare(vers[j]); c != expect {
+				t.Errorf("compare %s to %s, expected %d, received %d", ordered[i], ordered[j], expect, c)
+			}
+		}
+	}
+	rand.New(rand.NewSource(1)).Shuffle(len(vers), vers.Swap)
+	sort.Sort(vers)
+	for i, v := range vers {
+		if v.Original() != ordered[i] {
+			t.Errorf("unexpected sort order at %d, expected %s, received %s", i, ordered[i], v.Original())
+		}
+	}
+	// trailing zeros are equal
+	a, _ := Parse("1.0")
+	b, _ := Parse("1.0.0")
+	if a.Compare(b) != 0 {
+		t.Errorf("expected 1.0 and 1.0.0 to be equal")
+	}
+}
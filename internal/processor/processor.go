@@ -18,22 +18,31 @@ package processor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"maps"
 	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/Masterminds/semver/v3"
 
 	"github.com/sudo-bmitch/version-bump/internal/config"
 	"github.com/sudo-bmitch/version-bump/internal/lockfile"
+	"github.com/sudo-bmitch/version-bump/internal/pep440"
 	"github.com/sudo-bmitch/version-bump/internal/scan"
 	"github.com/sudo-bmitch/version-bump/internal/source"
 	"github.com/sudo-bmitch/version-bump/internal/template"
 )
 
+const (
+	sourceTypeLock   = "lock"
+	lockArgProcessor = "processor"
+	lockArgKey       = "key"
+)
+
 type processor struct {
 	Filename  string
 	Processor config.Processor
@@ -41,21 +50,68 @@ type processor struct {
 	Source    config.Source // clone of source with args overridden from Processor
 	locks     *lockfile.Locks
 	changes   []*Change
+	mode      Mode
+	key       string // only process matches with this key when set
+	matchFn   func(key string)
+	warnFn    func(msg string)
+}
+
+// Mode selects how the processor resolves the version for each match.
+type Mode int
+
+const (
+	ModeSource Mode = iota // query the source for the version, this is the default
+	ModeScan               // record the current version from the file into the lock without querying the source
+	ModeLock               // use the version from the lock, only querying the source for missing entries
+	ModeReset              // use the version from the lock without querying the source, missing entries are unchanged
+)
+
+// Opt is used to set options on [Process].
+type Opt func(*processor)
+
+// WithMode sets how versions are resolved.
+func WithMode(mode Mode) Opt {
+	return func(p *processor) {
+		p.mode = mode
+	}
+}
+
+// WithKey limits the processor to matches with the given key, other matches are left unchanged.
+func WithKey(key string) Opt {
+	return func(p *processor) {
+		p.key = key
+	}
+}
+
+// WithMatchFunc calls fn with the key of each match that is processed.
+// The function may be called concurrently when multiple processors are run.
+func WithMatchFunc(fn func(key string)) Opt {
+	return func(p *processor) {
+		p.matchFn = fn
+	}
+}
+
+// WithWarnFunc calls fn with warnings that do not stop the processor.
+// The function may be called concurrently when multiple processors are run.
+func WithWarnFunc(fn func(msg string)) Opt {
+	return func(p *processor) {
+		p.warnFn = fn
+	}
 }
 
 // Change lists changes found or made to scanned files.
 type Change struct {
-	Filename  string // filename modified
-	Processor string // name of the processor
-	Source    string // name of the source
-	Scan      string // name of the scan
-	Key       string // key from processor
-	Orig      string // previous version
-	New       string // new version
+	Filename  string `yaml:"filename" json:"filename"`   // filename modified
+	Processor string `yaml:"processor" json:"processor"` // name of the processor
+	Source    string `yaml:"source" json:"source"`       // name of the source
+	Scan      string `yaml:"scan" json:"scan"`           // name of the scan
+	Key       string `yaml:"key" json:"key"`             // key from processor
+	Orig      string `yaml:"orig" json:"orig"`           // previous version
+	New       string `yaml:"new" json:"new"`             // new version
 }
 
 // Process is used to process a single file with a single scanner and source.
-func Process(ctx context.Context, conf config.Config, procName, filename string, r io.Reader, w io.Writer, locks *lockfile.Locks) ([]*Change, error) {
+func Process(ctx context.Context, conf config.Config, procName, filename string, r io.Reader, w io.Writer, locks *lockfile.Locks, opts ...Opt) ([]*Change, error) {
 	var err error
 	cProcOrig, ok := conf.Processors[procName]
 	if !ok || cProcOrig == nil {
@@ -82,6 +138,9 @@ func Process(ctx context.Context, conf config.Config, procName, filename string,
 		locks:     locks,
 		changes:   []*Change{},
 	}
+	for _, opt := range opts {
+		opt(&p)
+	}
 	// template various fields
 	cScan.Args, err = templateArgs(cScan.Args, p)
 	if err != nil {
@@ -112,15 +171,45 @@ func (p *processor) getVer(curVer string, matchArgs map[string]string) (string,
 		return curVer, fmt.Errorf("failed to template key: processor=%v, %v", *p, err)
 	}
 	tdp.Processor.Key = key
-	// TODO: handle different options for source (read from current lock or real source)
-	results, err := source.Get(src)
-	if err != nil {
-		return curVer, fmt.Errorf("failed to query source %s: %v", src.Name, err)
+	if p.key != "" && p.key != key {
+		return curVer, nil
 	}
-	// filter, sort, and template results
-	newVer, err := p.resultsToVer(results, tdp)
-	if err != nil {
-		return curVer, err
+	if p.matchFn != nil {
+		p.matchFn(key)
+	}
+	var newVer string
+	switch p.mode {
+	case ModeScan:
+		// the version in the file is stored in the lock as is
+		newVer = curVer
+	case ModeLock:
+		// prefer the locked version, only new entries are queried from the source
+		l, lErr := p.locks.Get(p.Processor.Name, key)
+		if lErr == nil {
+			newVer = l.Version
+			break
+		}
+		if !errors.Is(lErr, lockfile.ErrNotFound) {
+			return curVer, lErr
+		}
+		newVer, err = p.sourceVer(src, tdp)
+		if err != nil {
+			return curVer, err
+		}
+	case ModeReset:
+		l, lErr := p.locks.Get(p.Processor.Name, key)
+		if errors.Is(lErr, lockfile.ErrNotFound) {
+			return curVer, nil
+		}
+		if lErr != nil {
+			return curVer, lErr
+		}
+		newVer = l.Version
+	default:
+		newVer, err = p.sourceVer(src, tdp)
+		if err != nil {
+			return curVer, err
+		}
 	}
 	// manage version locks
 	err = p.locks.Set(p.Processor.Name, key, newVer)
@@ -142,6 +231,81 @@ func (p *processor) getVer(curVer string, matchArgs map[string]string) (string,
 	return newVer, nil
 }
 
+// sourceVer queries the source and returns the selected version.
+func (p *processor) sourceVer(src config.Source, tdp tmplDataProcess) (string, error) {
+	var results source.Results
+	var err error
+	if src.Type == sourceTypeLock {
+		results, err = p.lockResults(src, tdp)
+	} else {
+		results, err = source.Get(src)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query source %s: %v", src.Name, err)
+	}
+	// filter, sort, and template results
+	return p.resultsToVer(results, tdp)
+}
+
+// lockResults returns the version of another processor from the locks, without querying its source.
+// The version should be set by the other processor earlier in the current run, see [Depth] to order the processors.
+// Otherwise the version from the lock file is used, with a warning when the source is being queried.
+// The processor arg cannot be templated since [Depth] orders processors before any matches are found.
+// The key arg defaults to the key of the current processor.
+func (p *processor) lockResults(src config.Source, tdp tmplDataProcess) (source.Results, error) {
+	if strings.Contains(p.Source.Args[lockArgProcessor], "{{") {
+		return source.Results{}, fmt.Errorf("processor argument cannot be templated: %s", p.Source.Args[lockArgProcessor])
+	}
+	procName := src.Args[lockArgProcessor]
+	if procName == "" {
+		return source.Results{}, fmt.Errorf("processor argument is required")
+	}
+	if procName == p.Processor.Name {
+		return source.Results{}, fmt.Errorf("lock source cannot reference its own processor: %s", procName)
+	}
+	key, ok := src.Args[lockArgKey]
+	if !ok {
+		key = tdp.Processor.Key
+	}
+	l, err := p.locks.GetCurrent(procName, key)
+	if errors.Is(err, lockfile.ErrNotFound) {
+		l, err = p.locks.Get(procName, key)
+		// a version from the lock file may be stale when the source is being queried
+		if err == nil && p.mode == ModeSource && p.warnFn != nil {
+			p.warnFn(fmt.Sprintf("processor %s has not resolved key %s in the current run, using %s from the lock file", procName, key, l.Version))
+		}
+	}
+	if err != nil {
+		return source.Results{}, fmt.Errorf("failed to get lock for processor %s, key %s: %w", procName, key, err)
+	}
+	return source.Results{
+		VerMap: map[string]string{
+			l.Version: l.Version,
+		},
+	}, nil
+}
+
+// Depth returns the number of lock sources between a processor and a processor that queries its source.
+// Running processors in order of their depth sets the locks used by a lock source before they are needed.
+func Depth(conf config.Config, procName string) int {
+	return depth(conf, procName, map[string]bool{})
+}
+
+func depth(conf config.Config, procName string, seen map[string]bool) int {
+	cProc, ok := conf.Processors[procName]
+	if !ok || cProc == nil || seen[procName] {
+		return 0
+	}
+	cSource, ok := conf.Sources[cProc.Source]
+	if !ok || cSource == nil || cSource.Type != sourceTypeLock {
+		return 0
+	}
+	seen[procName] = true
+	defer delete(seen, procName)
+	args := argsMerge(cSource.Args, cProc.SourceArgs)
+	return depth(conf, args[lockArgProcessor], seen) + 1
+}
+
 func (p *processor) resultsToVer(results source.Results, tdp tmplDataProcess) (string, error) {
 	// build a list of keys/versions that match the filter
 	var filterExp *regexp.Regexp
@@ -202,6 +366,27 @@ func (p *processor) resultsToVer(results source.Results, tdp tmplDataProcess) (s
 		for i, sv := range vers {
 			keys[i] = sv.Original()
 		}
+	case "pep440":
+		vers := make([]*pep440.Version, 0, len(keys))
+		for _, k := range keys {
+			pv, err := pep440.Parse(k)
+			if err != nil {
+				continue // ignore versions that do not parse
+			}
+			vers = append(vers, pv)
+		}
+		if len(vers) == 0 {
+			return "", fmt.Errorf("no valid pep440 versions found in %v", keys)
+		}
+		if p.Processor.Sort.Asc {
+			sort.Sort(pep440.Collection(vers))
+		} else {
+			sort.Sort(sort.Reverse(pep440.Collection(vers)))
+		}
+		keys = make([]string, len(vers))
+		for i, pv := range vers {
+			keys[i] = pv.Original()
+		}
 	case "numeric":
 		keyInts := make([]int, 0, len(keys))
 		orig := map[int]string{} // map from int back to original value
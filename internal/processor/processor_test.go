@@ -115,6 +115,8 @@ func TestProcessor(t *testing.T) {
 		conf         config.Config
 		procName     string
 		filename     string
+		opts         []Opt
+		locks        *lockfile.Locks
 		in           []byte
 		expectOut    []byte
 		expectChange []*Change
@@ -182,6 +184,309 @@ func TestProcessor(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:     "manual-scan",
+			filename: "test",
+			procName: "manual",
+			opts:     []Opt{WithMode(ModeScan)},
+			conf: config.Config{
+				Processors: map[string]*config.Processor{
+					"manual": {
+						Name: "manual",
+						Scan: "regexp",
+						ScanArgs: map[string]string{
+							"regexp": `^testVer=(?P<Version>[0-9.]+)`,
+						},
+						Source: "manual",
+						SourceArgs: map[string]string{
+							"Version": "4.3.2.1",
+						},
+						Key: "manual",
+					},
+				},
+				Scans: map[string]*config.Scan{
+					"regexp": {
+						Type: "regexp",
+					},
+				},
+				Sources: map[string]*config.Source{
+					"manual": {
+						Type: "manual",
+					},
+				},
+			},
+			in:           []byte(`testVer=1.2.3.4`),
+			expectOut:    []byte(`testVer=1.2.3.4`),
+			expectChange: []*Change{},
+			expectLocks: &lockfile.Locks{
+				Lock: map[string]map[string]*lockfile.Lock{
+					"manual": {
+						"manual": {
+							Name:    "manual",
+							Key:     "manual",
+							Version: `1.2.3.4`,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "manual-lock",
+			filename: "test",
+			procName: "manual",
+			opts:     []Opt{WithMode(ModeLock)},
+			locks: &lockfile.Locks{
+				Lock: map[string]map[string]*lockfile.Lock{
+					"manual": {
+						"manual": {
+							Name:    "manual",
+							Key:     "manual",
+							Version: `2.0`,
+						},
+					},
+				},
+			},
+			conf: config.Config{
+				Processors: map[string]*config.Processor{
+					"manual": {
+						Name: "manual",
+						Scan: "regexp",
+						ScanArgs: map[string]string{
+							"regexp": `^testVer=(?P<Version>[0-9.]+)`,
+						},
+						Source: "manual",
+						SourceArgs: map[string]string{
+							"Version": "4.3.2.1",
+						},
+						Key: "manual",
+					},
+				},
+				Scans: map[string]*config.Scan{
+					"regexp": {
+						Type: "regexp",
+					},
+				},
+				Sources: map[string]*config.Source{
+					"manual": {
+						Type: "manual",
+					},
+				},
+			},
+			in:        []byte(`testVer=1.2.3.4`),
+			expectOut: []byte(`testVer=2.0`),
+			expectChange: []*Change{
+				{
+					Filename:  "test",
+					Processor: "manual",
+					Source:    "manual",
+					Scan:      "regexp",
+					Key:       "manual",
+					Orig:      `1.2.3.4`,
+					New:       `2.0`,
+				},
+			},
+			expectLocks: &lockfile.Locks{
+				Lock: map[string]map[string]*lockfile.Lock{
+					"manual": {
+						"manual": {
+							Name:    "manual",
+							Key:     "manual",
+							Version: `2.0`,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "manual-lock-missing",
+			filename: "test",
+			procName: "manual",
+			opts:     []Opt{WithMode(ModeLock)},
+			conf: config.Config{
+				Processors: map[string]*config.Processor{
+					"manual": {
+						Name: "manual",
+						Scan: "regexp",
+						ScanArgs: map[string]string{
+							"regexp": `^testVer=(?P<Version>[0-9.]+)`,
+						},
+						Source: "manual",
+						SourceArgs: map[string]string{
+							"Version": "4.3.2.1",
+						},
+						Key: "manual",
+					},
+				},
+				Scans: map[string]*config.Scan{
+					"regexp": {
+						Type: "regexp",
+					},
+				},
+				Sources: map[string]*config.Source{
+					"manual": {
+						Type: "manual",
+					},
+				},
+			},
+			in:        []byte(`testVer=1.2.3.4`),
+			expectOut: []byte(`testVer=4.3.2.1`),
+			expectChange: []*Change{
+				{
+					Filename:  "test",
+					Processor: "manual",
+					Source:    "manual",
+					Scan:      "regexp",
+					Key:       "manual",
+					Orig:      `1.2.3.4`,
+					New:       `4.3.2.1`,
+				},
+			},
+			expectLocks: &lockfile.Locks{
+				Lock: map[string]map[string]*lockfile.Lock{
+					"manual": {
+						"manual": {
+							Name:    "manual",
+							Key:     "manual",
+							Version: `4.3.2.1`,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "manual-set-key",
+			filename: "test",
+			procName: "manual",
+			opts:     []Opt{WithMode(ModeLock), WithKey("a")},
+			locks: &lockfile.Locks{
+				Lock: map[string]map[string]*lockfile.Lock{
+					"manual": {
+						"a": {
+							Name:    "manual",
+							Key:     "a",
+							Version: `2.0`,
+						},
+					},
+				},
+			},
+			conf: config.Config{
+				Processors: map[string]*config.Processor{
+					"manual": {
+						Name: "manual",
+						Scan: "regexp",
+						ScanArgs: map[string]string{
+							"regexp": `^(?P<name>\w+)=(?P<Version>[0-9.]+)`,
+						},
+						Source: "manual",
+						SourceArgs: map[string]string{
+							"Version": "4.3.2.1",
+						},
+						Key: "{{ .ScanMatch.name }}",
+					},
+				},
+				Scans: map[string]*config.Scan{
+					"regexp": {
+						Type: "regexp",
+					},
+				},
+				Sources: map[string]*config.Source{
+					"manual": {
+						Type: "manual",
+					},
+				},
+			},
+			in:        []byte("a=1.0\nb=1.0"),
+			expectOut: []byte("a=2.0\nb=1.0"),
+			expectChange: []*Change{
+				{
+					Filename:  "test",
+					Processor: "manual",
+					Source:    "manual",
+					Scan:      "regexp",
+					Key:       "a",
+					Orig:      `1.0`,
+					New:       `2.0`,
+				},
+			},
+			expectLocks: &lockfile.Locks{
+				Lock: map[string]map[string]*lockfile.Lock{
+					"manual": {
+						"a": {
+							Name:    "manual",
+							Key:     "a",
+							Version: `2.0`,
+						},
+					},
+				},
+			},
+		},
+		{
+			name:     "manual-reset",
+			filename: "test",
+			procName: "manual",
+			opts:     []Opt{WithMode(ModeReset)},
+			locks: &lockfile.Locks{
+				Lock: map[string]map[string]*lockfile.Lock{
+					"manual": {
+						"a": {
+							Name:    "manual",
+							Key:     "a",
+							Version: `2.0`,
+						},
+					},
+				},
+			},
+			conf: config.Config{
+				Processors: map[string]*config.Processor{
+					"manual": {
+						Name: "manual",
+						Scan: "regexp",
+						ScanArgs: map[string]string{
+							"regexp": `^(?P<name>\w+)=(?P<Version>[0-9.]+)`,
+						},
+						Source: "manual",
+						SourceArgs: map[string]string{
+							"Version": "4.3.2.1",
+						},
+						Key: "{{ .ScanMatch.name }}",
+					},
+				},
+				Scans: map[string]*config.Scan{
+					"regexp": {
+						Type: "regexp",
+					},
+				},
+				Sources: map[string]*config.Source{
+					"manual": {
+						Type: "manual",
+					},
+				},
+			},
+			in:        []byte("a=1.0\nb=1.0"),
+			expectOut: []byte("a=2.0\nb=1.0"),
+			expectChange: []*Change{
+				{
+					Filename:  "test",
+					Processor: "manual",
+					Source:    "manual",
+					Scan:      "regexp",
+					Key:       "a",
+					Orig:      `1.0`,
+					New:       `2.0`,
+				},
+			},
+			expectLocks: &lockfile.Locks{
+				Lock: map[string]map[string]*lockfile.Lock{
+					"manual": {
+						"a": {
+							Name:    "manual",
+							Key:     "a",
+							Version: `2.0`,
+						},
+					},
+				},
+			},
+		},
 		{
 			name:     "filter-git-tag",
 			filename: "test",
@@ -378,8 +683,11 @@ func TestProcessor(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			bufIn := bytes.NewBuffer(tc.in)
 			bufOut := new(bytes.Buffer)
-			locks := lockfile.New()
-			resultChange, resultErr := Process(ctx, tc.conf, tc.procName, tc.filename, bufIn, bufOut, locks)
+			locks := tc.locks
+			if locks == nil {
+				locks = lockfile.New()
+			}
+			resultChange, resultErr := Process(ctx, tc.conf, tc.procName, tc.filename, bufIn, bufOut, locks, tc.opts...)
 			if tc.expectErr != nil {
 				if resultErr == nil || (!errors.Is(resultErr, tc.expectErr) && resultErr.Error() != tc.expectErr.Error()) {
 					t.Fatalf("expected error %v, received %v", tc.expectErr, resultErr)
@@ -421,6 +729,224 @@ func TestProcessor(t *testing.T) {
 	}
 }
 
+func TestProcessorLock(t *testing.T) {
+	ctx := context.TODO()
+	conf := config.Config{
+		Processors: map[string]*config.Processor{
+			"manual": {
+				Name: "manual",
+				Scan: "regexp",
+				ScanArgs: map[string]string{
+					"regexp": `^testVer=(?P<Version>[0-9.]+)`,
+				},
+				Source: "manual",
+				SourceArgs: map[string]string{
+					"Version": "4.3.2.1",
+				},
+				Key: "manual",
+			},
+			"lock": {
+				Name: "lock",
+				Scan: "regexp",
+				ScanArgs: map[string]string{
+					"regexp": `^lockVer=(?P<Version>v[0-9.]+)`,
+				},
+				Source: "lock",
+				SourceArgs: map[string]string{
+					"processor": "manual",
+					"key":       "manual",
+				},
+				Key:      "lock",
+				Template: "v{{ .Version }}",
+			},
+			"lock-default-key": {
+				Name: "lock-default-key",
+				Scan: "regexp",
+				ScanArgs: map[string]string{
+					"regexp": `^lockVer=(?P<Version>v[0-9.]+)`,
+				},
+				Source: "lock",
+				SourceArgs: map[string]string{
+					"processor": "manual",
+				},
+				Key:      "manual",
+				Template: "v{{ .Version }}",
+			},
+			"lock-self": {
+				Name: "lock-self",
+				Scan: "regexp",
+				ScanArgs: map[string]string{
+					"regexp": `^lockVer=(?P<Version>v[0-9.]+)`,
+				},
+				Source: "lock",
+				SourceArgs: map[string]string{
+					"processor": "lock-self",
+				},
+				Key: "manual",
+			},
+			"lock-template": {
+				Name: "lock-template",
+				Scan: "regexp",
+				ScanArgs: map[string]string{
+					"regexp": `^(?P<name>[a-z]+)Ver=(?P<Version>v[0-9.]+)`,
+				},
+				Source: "lock",
+				SourceArgs: map[string]string{
+					"processor": "{{ .ScanMatch.name }}",
+				},
+				Key: "manual",
+			},
+			"lock-missing-arg": {
+				Name: "lock-missing-arg",
+				Scan: "regexp",
+				ScanArgs: map[string]string{
+					"regexp": `^lockVer=(?P<Version>v[0-9.]+)`,
+				},
+				Source: "lock",
+				Key:    "manual",
+			},
+		},
+		Scans: map[string]*config.Scan{
+			"regexp": {
+				Type: "regexp",
+			},
+		},
+		Sources: map[string]*config.Source{
+			"manual": {
+				Type: "manual",
+			},
+			"lock": {
+				Type: "lock",
+			},
+		},
+	}
+	lockFile := func() *lockfile.Locks {
+		return &lockfile.Locks{
+			Lock: map[string]map[string]*lockfile.Lock{
+				"manual": {
+					"manual": {
+						Name:    "manual",
+						Key:     "manual",
+						Version: "2.0",
+					},
+				},
+			},
+		}
+	}
+	run := func(t *testing.T, procName string, locks *lockfile.Locks, in string, opts ...Opt) (string, error) {
+		t.Helper()
+		out := new(bytes.Buffer)
+		_, err := Process(ctx, conf, procName, "test", bytes.NewBufferString(in), out, locks, opts...)
+		return out.String(), err
+	}
+
+	t.Run("current run", func(t *testing.T) {
+		locks := lockFile()
+		out, err := run(t, "manual", locks, "testVer=1.2.3.4")
+		if err != nil || out != "testVer=4.3.2.1" {
+			t.Fatalf("unexpected manual result: %s, %v", out, err)
+		}
+		out, err = run(t, "lock", locks, "lockVer=v1.2.3.4")
+		if err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		if out != "lockVer=v4.3.2.1" {
+			t.Errorf("unexpected output: %s", out)
+		}
+		l, err := locks.Get("lock", "lock")
+		if err != nil || l.Version != "v4.3.2.1" {
+			t.Errorf("unexpected lock: %v, %v", l, err)
+		}
+	})
+	t.Run("lock file", func(t *testing.T) {
+		warnings := []string{}
+		out, err := run(t, "lock", lockFile(), "lockVer=v1.2.3.4", WithMode(ModeLock),
+			WithWarnFunc(func(msg string) { warnings = append(warnings, msg) }))
+		if err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		if out != "lockVer=v2.0" {
+			t.Errorf("unexpected output: %s", out)
+		}
+		if len(warnings) != 0 {
+			t.Errorf("unexpected warnings: %v", warnings)
+		}
+	})
+	t.Run("lock file stale", func(t *testing.T) {
+		warnings := []string{}
+		out, err := run(t, "lock", lockFile(), "lockVer=v1.2.3.4",
+			WithWarnFunc(func(msg string) { warnings = append(warnings, msg) }))
+		if err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		if out != "lockVer=v2.0" {
+			t.Errorf("unexpected output: %s", out)
+		}
+		if len(warnings) != 1 {
+			t.Errorf("expected a warning, received %v", warnings)
+		}
+	})
+	t.Run("default key", func(t *testing.T) {
+		out, err := run(t, "lock-default-key", lockFile(), "lockVer=v1.2.3.4", WithMode(ModeLock))
+		if err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		if out != "lockVer=v2.0" {
+			t.Errorf("unexpected output: %s", out)
+		}
+	})
+	for _, procName := range []string{"lock-self", "lock-missing-arg"} {
+		t.Run(procName, func(t *testing.T) {
+			if _, err := run(t, procName, lockFile(), "lockVer=v1.2.3.4"); err == nil {
+				t.Errorf("did not fail")
+			}
+		})
+	}
+	t.Run("templated processor", func(t *testing.T) {
+		// the templated name resolves to a processor in the lock, but the processors cannot be ordered by Depth
+		if _, err := run(t, "lock-template", lockFile(), "manualVer=v1.2.3.4", WithMode(ModeLock)); err == nil {
+			t.Errorf("did not fail")
+		}
+	})
+	t.Run("missing lock", func(t *testing.T) {
+		if _, err := run(t, "lock", lockfile.New(), "lockVer=v1.2.3.4", WithMode(ModeLock)); err == nil {
+			t.Errorf("did not fail")
+		}
+	})
+	t.Run("depth", func(t *testing.T) {
+		conf.Processors["lock-chain"] = &config.Processor{
+			Name:       "lock-chain",
+			Source:     "lock",
+			SourceArgs: map[string]string{"processor": "lock"},
+		}
+		conf.Processors["lock-loop"] = &config.Processor{
+			Name:       "lock-loop",
+			Source:     "lock",
+			SourceArgs: map[string]string{"processor": "lock-loop-back"},
+		}
+		conf.Processors["lock-loop-back"] = &config.Processor{
+			Name:       "lock-loop-back",
+			Source:     "lock",
+			SourceArgs: map[string]string{"processor": "lock-loop"},
+		}
+		expect := map[string]int{
+			"manual":           0,
+			"lock":             1,
+			"lock-default-key": 1,
+			"lock-chain":       2,
+			"lock-self":        1,
+			"lock-loop":        2,
+			"lock-missing-arg": 1,
+			"missing":          0,
+		}
+		for procName, exp := range expect {
+			if result := Depth(conf, procName); result != exp {
+				t.Errorf("unexpected depth for %s, expected %d, received %d", procName, exp, result)
+			}
+		}
+	})
+}
+
 func TestResultsToVer(t *testing.T) {
 	tt := []struct {
 		name    string
@@ -486,6 +1012,63 @@ func TestResultsToVer(t *testing.T) {
 			},
 			expect: "1.3.3",
 		},
+		{
+			name: "pep440",
+			p: processor{
+				Filename: "test-pep440",
+				Processor: config.Processor{
+					Name: "pep440",
+					Filter: config.Filter{
+						Expr: `^[0-9.]+(\.post[0-9]+)?$`,
+					},
+					Sort: config.Sort{
+						Method: "pep440",
+					},
+				},
+			},
+			results: source.Results{
+				VerMap: map[string]string{
+					"1.0":       "1.0",
+					"1.0rc1":    "1.0rc1",
+					"1.0.post1": "1.0.post1",
+					"1.0.post2": "1.0.post2",
+					"1.0.1rc1":  "1.0.1rc1",
+					"0.9":       "0.9",
+				},
+			},
+			tdp: tmplDataProcess{
+				ScanMatch: map[string]string{},
+			},
+			expect: "1.0.post2",
+		},
+		{
+			name: "pep440-asc-prerelease",
+			p: processor{
+				Filename: "test-pep440-asc",
+				Processor: config.Processor{
+					Name: "pep440",
+					Sort: config.Sort{
+						Method: "pep440",
+						Asc:    true,
+						Offset: 1,
+					},
+				},
+			},
+			results: source.Results{
+				VerMap: map[string]string{
+					"1.0":        "1.0",
+					"1.0rc1":     "1.0rc1",
+					"1.0.dev1":   "1.0.dev1",
+					"not-a-ver":  "not-a-ver",
+					"1.0.post1":  "1.0.post1",
+					"1.0a1.dev2": "1.0a1.dev2",
+				},
+			},
+			tdp: tmplDataProcess{
+				ScanMatch: map[string]string{},
+			},
+			expect: "1.0a1.dev2",
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
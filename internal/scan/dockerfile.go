@@ -0,0 +1,381 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+const (
+	dockerfileArgField     = "field"
+	dockerfileImage        = "Image"
+	dockerfileTag          = "Tag"
+	dockerfileDigest       = "Digest"
+	dockerfileStage        = "Stage"
+	dockerfilePlatform     = "Platform"
+	dockerfileInstruction  = "Instruction"
+	dockerfileDefaultField = dockerfileTag
+)
+
+var (
+	dockerfileDirectiveRE = regexp.MustCompile(`^#\s*([a-zA-Z][a-zA-Z0-9]*)\s*=\s*(.+?)\s*$`)
+	dockerfileHeredocRE   = regexp.MustCompile(`<<(-?)(["']?)([a-zA-Z_][a-zA-Z0-9_]*)["']?`)
+)
+
+// runDockerfileScan executes a scanner on the image references in a Dockerfile.
+// References in FROM and COPY --from are parsed, expanding any ARG values.
+// The field arg selects the Tag (default) or Digest to update, which may be defined in the default value of an ARG.
+func runDockerfileScan(ctx context.Context, conf config.Scan, filename string, r io.Reader, w io.Writer, getVer func(curVer string, args map[string]string) (string, error)) error {
+	field := conf.Args[dockerfileArgField]
+	if field == "" {
+		field = dockerfileDefaultField
+	}
+	if field != dockerfileTag && field != dockerfileDigest {
+		return fmt.Errorf("scan field must be %s or %s for %s: %s", dockerfileTag, dockerfileDigest, conf.Name, field)
+	}
+	re, err := valueRegexp(conf)
+	if err != nil {
+		return err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	refs := dockerfileRefs(b)
+	matches := []valueMatch{}
+	for _, ref := range refs {
+		image, tag, digest := ref.split()
+		value := tag
+		if field == dockerfileDigest {
+			value = digest
+		}
+		start, end, ok := value.span()
+		if !ok {
+			// the value is missing or not defined in this file
+			continue
+		}
+		matches = append(matches, valueMatch{
+			start: start,
+			end:   end,
+			value: value.s,
+			encode: func(s string) string {
+				return s
+			},
+			args: map[string]string{
+				dockerfileImage:       image.s,
+				dockerfileTag:         tag.s,
+				dockerfileDigest:      digest.s,
+				dockerfileStage:       ref.stage,
+				dockerfilePlatform:    ref.platform,
+				dockerfileInstruction: ref.instruction,
+			},
+		})
+	}
+	return writeValues(b, matches, re, w, getVer)
+}
+
+// dfText is text parsed from a Dockerfile, tracking the offset in the source of each byte.
+// Offsets are -1 for bytes that are not in the source.
+type dfText struct {
+	s    string
+	offs []int
+}
+
+func (t dfText) slice(i, j int) dfText {
+	return dfText{s: t.s[i:j], offs: t.offs[i:j]}
+}
+
+func (t *dfText) appendByte(c byte, off int) {
+	t.s += string(c)
+	t.offs = append(t.offs, off)
+}
+
+func (t *dfText) appendText(o dfText) {
+	t.s += o.s
+	t.offs = append(t.offs, o.offs...)
+}
+
+// span returns the offsets in the source when the text is a single unmodified section of the source.
+func (t dfText) span() (int, int, bool) {
+	if len(t.offs) == 0 || t.offs[0] < 0 {
+		return 0, 0, false
+	}
+	for i := 1; i < len(t.offs); i++ {
+		if t.offs[i] != t.offs[0]+i {
+			return 0, 0, false
+		}
+	}
+	return t.offs[0], t.offs[0] + len(t.offs), true
+}
+
+// dfRef is an image reference in a Dockerfile.
+type dfRef struct {
+	ref         dfText
+	instruction string
+	stage       string
+	platform    string
+}
+
+// split returns the image, tag, and digest from the reference.
+func (r dfRef) split() (image, tag, digest dfText) {
+	image = r.ref
+	if i := strings.Index(image.s, "@"); i >= 0 {
+		digest = image.slice(i+1, len(image.s))
+		image = image.slice(0, i)
+	}
+	if i := strings.LastIndex(image.s, ":"); i > strings.LastIndex(image.s, "/") {
+		tag = image.slice(i+1, len(image.s))
+		image = image.slice(0, i)
+	}
+	return image, tag, digest
+}
+
+// dockerfileRefs returns the external image references from a Dockerfile.
+// Stage names, scratch, and references with unknown variables are skipped.
+func dockerfileRefs(b []byte) []dfRef {
+	escape, lines := dockerfileLines(b)
+	refs := []dfRef{}
+	globalArgs := map[string]dfText{}
+	stageArgs := globalArgs
+	stageNames := map[string]bool{}
+	stageCount := 0
+	curStage, curPlatform := "", ""
+	external := func(ref string) bool {
+		return ref != "" && !strings.Contains(ref, "$") && !strings.EqualFold(ref, "scratch") && !stageNames[strings.ToLower(ref)]
+	}
+	for _, line := range lines {
+		cmd, rest := line, dfText{}
+		if i := strings.IndexAny(line.s, " \t"); i >= 0 {
+			cmd, rest = line.slice(0, i), line.slice(i+1, len(line.s))
+		}
+		switch strings.ToUpper(cmd.s) {
+		case "ARG":
+			for _, word := range dfLex(rest, stageArgs, escape) {
+				name, _, ok := strings.Cut(word.s, "=")
+				if ok {
+					stageArgs[name] = word.slice(len(name)+1, len(word.s))
+				} else if v, ok := globalArgs[name]; ok {
+					// a stage may import the default value of a global arg
+					stageArgs[name] = v
+				} else {
+					stageArgs[name] = dfText{}
+				}
+			}
+		case "FROM":
+			words := dfLex(rest, globalArgs, escape)
+			curPlatform = ""
+			for len(words) > 0 && strings.HasPrefix(words[0].s, "--") {
+				if v, ok := strings.CutPrefix(words[0].s, "--platform="); ok {
+					curPlatform = v
+				}
+				words = words[1:]
+			}
+			if len(words) == 0 {
+				continue
+			}
+			curStage = strconv.Itoa(stageCount)
+			if len(words) >= 3 && strings.EqualFold(words[1].s, "AS") {
+				curStage = words[2].s
+			}
+			if external(words[0].s) {
+				refs = append(refs, dfRef{
+					ref:         words[0],
+					instruction: "FROM",
+					stage:       curStage,
+					platform:    curPlatform,
+				})
+			}
+			stageNames[strings.ToLower(curStage)] = true
+			stageCount++
+			stageArgs = map[string]dfText{}
+		case "COPY":
+			for _, word := range dfLex(rest, stageArgs, escape) {
+				if !strings.HasPrefix(word.s, "--") {
+					break
+				}
+				if from, ok := strings.CutPrefix(word.s, "--from="); ok && external(from) {
+					refs = append(refs, dfRef{
+						ref:         word.slice(len("--from="), len(word.s)),
+						instruction: "COPY",
+						stage:       curStage,
+						platform:    curPlatform,
+					})
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// dockerfileLines returns the escape character and the list of instructions, joining any continued lines.
+// Parser directives, comments, empty lines, and heredocs are removed.
+func dockerfileLines(b []byte) (byte, []dfText) {
+	escape := byte('\\')
+	lines := []dfText{}
+	cur := dfText{}
+	cont := false
+	directives := true
+	heredocs := []string{}
+	for pos := 0; pos < len(b); {
+		start := pos
+		end := len(b)
+		if i := bytes.IndexByte(b[pos:], '\n'); i >= 0 {
+			end = pos + i
+		}
+		pos = end + 1
+		end = start + len(bytes.TrimRight(b[start:end], "\r"))
+		line := b[start:end]
+		trimmed := bytes.TrimSpace(line)
+		if len(heredocs) > 0 {
+			if string(trimmed) == heredocs[0] {
+				heredocs = heredocs[1:]
+			}
+			continue
+		}
+		if directives {
+			if m := dockerfileDirectiveRE.FindSubmatch(line); m != nil {
+				if strings.EqualFold(string(m[1]), "escape") && len(m[2]) == 1 && (m[2][0] == '`' || m[2][0] == '\\') {
+					escape = m[2][0]
+				}
+				continue
+			}
+			directives = false
+		}
+		if len(trimmed) == 0 || trimmed[0] == '#' {
+			continue
+		}
+		if !cont {
+			// skip leading whitespace on the instruction
+			start = end - len(bytes.TrimLeft(line, " \t"))
+		}
+		lineEnd := start + len(bytes.TrimRight(b[start:end], " \t"))
+		cont = lineEnd > start && b[lineEnd-1] == escape
+		if cont {
+			lineEnd--
+		}
+		for i := start; i < lineEnd; i++ {
+			cur.appendByte(b[i], i)
+		}
+		if cont {
+			continue
+		}
+		lines = append(lines, cur)
+		cmd, _, _ := strings.Cut(cur.s, " ")
+		switch strings.ToUpper(cmd) {
+		case "RUN", "COPY", "ADD":
+			for _, m := range dockerfileHeredocRE.FindAllStringSubmatch(cur.s, -1) {
+				heredocs = append(heredocs, m[3])
+			}
+		}
+		cur = dfText{}
+	}
+	if len(cur.s) > 0 {
+		lines = append(lines, cur)
+	}
+	return escape, lines
+}
+
+// dfLex splits the text into words, removing quotes and expanding variables.
+// Variables that are not defined are left unexpanded.
+func dfLex(t dfText, vars map[string]dfText, escape byte) []dfText {
+	words := []dfText{}
+	cur := dfText{}
+	inWord := false
+	quote := byte(0)
+	for i := 0; i < len(t.s); i++ {
+		c := t.s[i]
+		switch {
+		case quote == 0 && (c == ' ' || c == '\t'):
+			if inWord {
+				words = append(words, cur)
+				cur = dfText{}
+				inWord = false
+			}
+			continue
+		case c == escape && quote != '\'' && i+1 < len(t.s):
+			i++
+			cur.appendByte(t.s[i], t.offs[i])
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote != 0 && c == quote:
+			quote = 0
+		case c == '$' && quote != '\'':
+			v, n := dfExpand(t.slice(i, len(t.s)), vars)
+			cur.appendText(v)
+			i += n - 1
+		default:
+			cur.appendByte(c, t.offs[i])
+		}
+		inWord = true
+	}
+	if inWord {
+		words = append(words, cur)
+	}
+	return words
+}
+
+// dfExpand expands the variable at the start of the text, returning the value and the length of the variable.
+// The forms $name, ${name}, ${name:-default}, and ${name:+alternate} are supported.
+func dfExpand(t dfText, vars map[string]dfText) (dfText, int) {
+	braces := len(t.s) > 1 && t.s[1] == '{'
+	nameStart := 1
+	if braces {
+		nameStart = 2
+	}
+	nameEnd := nameStart
+	for nameEnd < len(t.s) && (t.s[nameEnd] == '_' || ('a' <= t.s[nameEnd] && t.s[nameEnd] <= 'z') || ('A' <= t.s[nameEnd] && t.s[nameEnd] <= 'Z') || ('0' <= t.s[nameEnd] && t.s[nameEnd] <= '9')) {
+		nameEnd++
+	}
+	if nameEnd == nameStart {
+		return t.slice(0, 1), 1
+	}
+	name := t.s[nameStart:nameEnd]
+	val, ok := vars[name]
+	if !braces {
+		if !ok {
+			return t.slice(0, nameEnd), nameEnd
+		}
+		return val, nameEnd
+	}
+	end := strings.IndexByte(t.s, '}')
+	if end < nameEnd {
+		return t.slice(0, 1), 1
+	}
+	switch modifier := t.s[nameEnd:end]; {
+	case modifier == "":
+		if !ok {
+			return t.slice(0, end+1), end + 1
+		}
+	case strings.HasPrefix(modifier, ":-"):
+		if val.s == "" {
+			val = t.slice(nameEnd+2, end)
+		}
+	case strings.HasPrefix(modifier, ":+"):
+		if val.s != "" {
+			val = t.slice(nameEnd+2, end)
+		}
+	default:
+		return t.slice(0, end+1), end + 1
+	}
+	return val, end + 1
+}
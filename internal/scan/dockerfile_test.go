@@ -0,0 +1,250 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestDockerfile(t *testing.T) {
+	ctx := context.Background()
+	getVerTemplate := func(tmpl string) func(curVer string, args map[string]string) (string, error) {
+		return func(curVer string, args map[string]string) (string, error) {
+			return fmt.Sprintf(tmpl, args["Image"], args["Stage"], args["Platform"], args["Instruction"]), nil
+		}
+	}
+
+	tests := []struct {
+		name     string
+		confScan config.Scan
+		getVer   func(curVer string, args map[string]string) (string, error)
+		in       []byte
+		expError error
+		expOut   []byte
+	}{
+		{
+			name: "invalid field",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "dockerfile",
+				Args: map[string]string{
+					"field": "Image",
+				},
+			},
+			getVer:   getVer10,
+			in:       []byte("FROM alpine:3\n"),
+			expError: fmt.Errorf("scan field must be Tag or Digest for test: Image"),
+		},
+		{
+			name: "from tag",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "dockerfile",
+			},
+			getVer: getVer10,
+			in: []byte(`FROM golang:1.22-alpine@sha256:1234 AS build
+RUN echo hello
+from alpine:3.19
+FROM scratch
+FROM build
+FROM registry.example.com:5000/app
+`),
+			expOut: []byte(`FROM golang:10@sha256:1234 AS build
+RUN echo hello
+from alpine:10
+FROM scratch
+FROM build
+FROM registry.example.com:5000/app
+`),
+		},
+		{
+			name: "from digest",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "dockerfile",
+				Args: map[string]string{
+					"field": "Digest",
+				},
+			},
+			getVer: getVerArgs("Tag"),
+			in:     []byte("FROM golang:1.22@sha256:1234\nFROM alpine:3.19\n"),
+			expOut: []byte("FROM golang:1.22@1.22\nFROM alpine:3.19\n"),
+		},
+		{
+			name: "scan match",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "dockerfile",
+			},
+			getVer: getVerTemplate("%s-%s-%s-%s"),
+			in: []byte(`FROM --platform=$BUILDPLATFORM golang:1.22 AS build
+FROM alpine:3.19
+COPY --from=build /app /app
+COPY --chown=0:0 --from=busybox:1.36 /bin/busybox /bin/busybox
+`),
+			expOut: []byte(`FROM --platform=$BUILDPLATFORM golang:golang-build-$BUILDPLATFORM-FROM AS build
+FROM alpine:alpine-1--FROM
+COPY --from=build /app /app
+COPY --chown=0:0 --from=busybox:busybox-1--COPY /bin/busybox /bin/busybox
+`),
+		},
+		{
+			name: "arg defaults",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "dockerfile",
+			},
+			getVer: getVerArgs("Image"),
+			in: []byte(`ARG REGISTRY=docker.io
+ARG GO_VER=1.22-alpine@sha256:1234
+ARG ALPINE_VER="3.19"
+FROM ${REGISTRY}/library/golang:${GO_VER} AS build
+FROM $REGISTRY/library/alpine:${ALPINE_VER:-3} AS release
+FROM ${REGISTRY}/library/golang:${GO_VER} AS test
+ARG BUSYBOX_VER=1.36
+COPY --from=busybox:${BUSYBOX_VER} /bin/busybox /bin/busybox
+FROM busybox:${BUSYBOX_VER}
+FROM debian:${DEBIAN_VER:-bookworm}
+`),
+			expOut: []byte(`ARG REGISTRY=docker.io
+ARG GO_VER=docker.io/library/golang@sha256:1234
+ARG ALPINE_VER="docker.io/library/alpine"
+FROM ${REGISTRY}/library/golang:${GO_VER} AS build
+FROM $REGISTRY/library/alpine:${ALPINE_VER:-3} AS release
+FROM ${REGISTRY}/library/golang:${GO_VER} AS test
+ARG BUSYBOX_VER=busybox
+COPY --from=busybox:${BUSYBOX_VER} /bin/busybox /bin/busybox
+FROM busybox:${BUSYBOX_VER}
+FROM debian:${DEBIAN_VER:-debian}
+`),
+		},
+		{
+			name: "stage args",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "dockerfile",
+			},
+			getVer: getVer10,
+			in: []byte(`ARG VER=1.0
+FROM alpine:3
+ARG VER
+ARG OTHER=2.0
+COPY --from=app:${VER} /a /a
+COPY --from=other:${OTHER} /b /b
+`),
+			expOut: []byte(`ARG VER=10
+FROM alpine:10
+ARG VER
+ARG OTHER=10
+COPY --from=app:${VER} /a /a
+COPY --from=other:${OTHER} /b /b
+`),
+		},
+		{
+			name: "conflicting arg",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "dockerfile",
+			},
+			getVer:   getVerArgs("Stage"),
+			in:       []byte("ARG VER=1.0\nFROM alpine:${VER} AS a\nFROM alpine:${VER} AS b\n"),
+			expError: fmt.Errorf("conflicting changes to the value at offset 8: a and b"),
+		},
+		{
+			name: "continuations and comments",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "dockerfile",
+			},
+			getVer: getVer10,
+			in: []byte(`# syntax=docker/dockerfile:1
+FROM \
+  # comment within the instruction
+  --platform=linux/amd64 \
+
+  alpine:3.19 \
+  AS base
+RUN <<EOF
+FROM alpine:3.18
+EOF
+  FROM busybox:1.36
+`),
+			expOut: []byte(`# syntax=docker/dockerfile:1
+FROM \
+  # comment within the instruction
+  --platform=linux/amd64 \
+
+  alpine:10 \
+  AS base
+RUN <<EOF
+FROM alpine:3.18
+EOF
+  FROM busybox:10
+`),
+		},
+		{
+			name: "escape directive",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "dockerfile",
+			},
+			getVer: getVer10,
+			in:     []byte("# escape=`\nFROM `\n  mcr.microsoft.com/windows/servercore:ltsc2022\nCOPY C:\\app\\ C:\\app\\\n"),
+			expOut: []byte("# escape=`\nFROM `\n  mcr.microsoft.com/windows/servercore:10\nCOPY C:\\app\\ C:\\app\\\n"),
+		},
+		{
+			name: "regexp",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "dockerfile",
+				Args: map[string]string{
+					"regexp": `^(?P<Version>\d+\.\d+)-(?P<Variant>.*)$`,
+				},
+			},
+			getVer: getVer10,
+			in:     []byte("FROM golang:1.22-alpine\nFROM golang:latest\n"),
+			expOut: []byte("FROM golang:10-alpine\nFROM golang:latest\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader(tt.in)
+			outBuf := bytes.NewBuffer([]byte{})
+			err := runDockerfileScan(ctx, tt.confScan, "test", r, outBuf, tt.getVer)
+			if tt.expError != nil {
+				if err == nil {
+					t.Errorf("runDockerfileScan did not fail")
+				} else if !errors.Is(err, tt.expError) && err.Error() != tt.expError.Error() {
+					t.Errorf("runDockerfileScan unexpected error, expected %v, received %v", tt.expError, err)
+				}
+				return
+			} else if err != nil {
+				t.Errorf("runDockerfileScan failed: %v", err)
+				return
+			}
+			out := outBuf.Bytes()
+			if !bytes.Equal(tt.expOut, out) {
+				t.Errorf("result does not match:\n--- expected ---\n%s\n--- received ---\n%s", tt.expOut, out)
+			}
+		})
+	}
+}
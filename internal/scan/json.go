@@ -0,0 +1,245 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+	"github.com/sudo-bmitch/version-bump/internal/datapath"
+)
+
+const (
+	jsonArgPath = "path"
+	jsonPath    = "Path"
+)
+
+// runJSONScan executes a scanner on a JSON file, selecting string values with a path.
+// Only the matched strings are replaced, preserving the formatting of the rest of the file.
+func runJSONScan(ctx context.Context, conf config.Scan, filename string, r io.Reader, w io.Writer, getVer func(curVer string, args map[string]string) (string, error)) error {
+	if _, ok := conf.Args[jsonArgPath]; !ok {
+		return fmt.Errorf("scan path arg is missing for %s", conf.Name)
+	}
+	path, err := datapath.Parse(conf.Args[jsonArgPath])
+	if err != nil {
+		return fmt.Errorf("scan path is invalid for %s: %w", conf.Name, err)
+	}
+	re, err := valueRegexp(conf)
+	if err != nil {
+		return err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	js := jsonScan{
+		src:     b,
+		path:    path,
+		matches: []valueMatch{},
+	}
+	js.skipSpace()
+	_, _, err = js.value(datapath.Steps{})
+	if err == nil {
+		js.skipSpace()
+		if js.pos < len(js.src) {
+			err = fmt.Errorf("unexpected content after the value at offset %d", js.pos)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse json in %s: %w", filename, err)
+	}
+	return writeValues(b, js.matches, re, w, getVer)
+}
+
+type jsonScan struct {
+	src     []byte
+	pos     int
+	path    *datapath.Path
+	matches []valueMatch
+}
+
+type jsonKind int
+
+const (
+	jsonString    jsonKind = iota
+	jsonScalar             // number, bool, or null
+	jsonContainer          // object or array
+)
+
+// value parses the value at the current position.
+// The returned string is the decoded value for strings, and the raw value for other scalars.
+// String values matching the path are added to the list of matches, other scalars are an error when the path is exact.
+func (js *jsonScan) value(steps datapath.Steps) (jsonKind, string, error) {
+	if js.pos >= len(js.src) {
+		return 0, "", io.ErrUnexpectedEOF
+	}
+	switch js.src[js.pos] {
+	case '{':
+		return jsonContainer, "", js.object(steps)
+	case '[':
+		return jsonContainer, "", js.array(steps)
+	case '"':
+		start := js.pos
+		s, err := js.str()
+		if err != nil {
+			return 0, "", err
+		}
+		if js.path.Match(steps) {
+			js.matches = append(js.matches, valueMatch{
+				start:  start,
+				end:    js.pos,
+				value:  s,
+				encode: jsonEncodeString,
+				args:   map[string]string{jsonPath: steps.String()},
+			})
+		}
+		return jsonString, s, nil
+	default:
+		start := js.pos
+		for js.pos < len(js.src) && !strings.ContainsRune(" \t\r\n,]}", rune(js.src[js.pos])) {
+			js.pos++
+		}
+		raw := string(js.src[start:js.pos])
+		var v any
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return 0, "", fmt.Errorf("invalid value at offset %d: %w", start, err)
+		}
+		// wildcard and recursive paths skip other scalars, e.g. a numeric version field in an unrelated object
+		if js.path.Match(steps) && js.path.Exact() {
+			return 0, "", fmt.Errorf("value at %s is not a string: %s", steps, raw)
+		}
+		return jsonScalar, raw, nil
+	}
+}
+
+func (js *jsonScan) object(steps datapath.Steps) error {
+	js.pos++ // skip the opening brace
+	// direct children that match are given the scalar fields of this object after it has been parsed
+	direct := []int{}
+	fields := map[string]string{}
+	js.skipSpace()
+	if js.pos < len(js.src) && js.src[js.pos] == '}' {
+		js.pos++
+		return nil
+	}
+	for {
+		js.skipSpace()
+		if js.pos >= len(js.src) || js.src[js.pos] != '"' {
+			return fmt.Errorf("expected object key at offset %d", js.pos)
+		}
+		key, err := js.str()
+		if err != nil {
+			return err
+		}
+		js.skipSpace()
+		if js.pos >= len(js.src) || js.src[js.pos] != ':' {
+			return fmt.Errorf("expected colon at offset %d", js.pos)
+		}
+		js.pos++
+		js.skipSpace()
+		count := len(js.matches)
+		kind, v, err := js.value(steps.Append(datapath.KeyStep(key)))
+		if err != nil {
+			return err
+		}
+		if kind != jsonContainer {
+			fields[key] = v
+			if len(js.matches) > count {
+				direct = append(direct, count)
+			}
+		}
+		js.skipSpace()
+		if js.pos >= len(js.src) {
+			return io.ErrUnexpectedEOF
+		}
+		switch js.src[js.pos] {
+		case ',':
+			js.pos++
+		case '}':
+			js.pos++
+			for _, i := range direct {
+				args := maps.Clone(fields)
+				maps.Copy(args, js.matches[i].args)
+				js.matches[i].args = args
+			}
+			return nil
+		default:
+			return fmt.Errorf("expected comma or closing brace at offset %d", js.pos)
+		}
+	}
+}
+
+func (js *jsonScan) array(steps datapath.Steps) error {
+	js.pos++ // skip the opening bracket
+	js.skipSpace()
+	if js.pos < len(js.src) && js.src[js.pos] == ']' {
+		js.pos++
+		return nil
+	}
+	for i := 0; ; i++ {
+		js.skipSpace()
+		if _, _, err := js.value(steps.Append(datapath.IndexStep(i))); err != nil {
+			return err
+		}
+		js.skipSpace()
+		if js.pos >= len(js.src) {
+			return io.ErrUnexpectedEOF
+		}
+		switch js.src[js.pos] {
+		case ',':
+			js.pos++
+		case ']':
+			js.pos++
+			return nil
+		default:
+			return fmt.Errorf("expected comma or closing bracket at offset %d", js.pos)
+		}
+	}
+}
+
+// str parses and decodes the string at the current position.
+func (js *jsonScan) str() (string, error) {
+	start := js.pos
+	end := quoteEnd(js.src, start, '"', '\\')
+	if end < 0 {
+		return "", fmt.Errorf("unterminated string at offset %d", start)
+	}
+	js.pos = end
+	var s string
+	if err := json.Unmarshal(js.src[start:end], &s); err != nil {
+		return "", fmt.Errorf("invalid string at offset %d: %w", start, err)
+	}
+	return s, nil
+}
+
+func (js *jsonScan) skipSpace() {
+	for js.pos < len(js.src) && strings.ContainsRune(" \t\r\n", rune(js.src[js.pos])) {
+		js.pos++
+	}
+}
+
+func jsonEncodeString(s string) string {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,221 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestJSON(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		confScan config.Scan
+		getVer   func(curVer string, args map[string]string) (string, error)
+		in       []byte
+		expError error
+		expOut   []byte
+	}{
+		{
+			name: "missing path",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+			},
+			getVer:   getVer10,
+			in:       []byte(`{}`),
+			expError: fmt.Errorf("scan path arg is missing for test"),
+		},
+		{
+			name: "package dependencies",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+				Args: map[string]string{
+					"path":   "$.dependencies.*",
+					"regexp": `^\^?(?P<Version>.*)$`,
+				},
+			},
+			getVer: getVer10,
+			in: []byte(`{
+    "name": "example",
+    "version": "1.0.0",
+    "dependencies": {
+        "@scope/pkg":   "^1.2.3",
+        "other" : "4.5.6"
+    },
+    "private": true
+}
+`),
+			expOut: []byte(`{
+    "name": "example",
+    "version": "1.0.0",
+    "dependencies": {
+        "@scope/pkg":   "^10",
+        "other" : "10"
+    },
+    "private": true
+}
+`),
+		},
+		{
+			name: "pointer",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+				Args: map[string]string{
+					"path": "/features/ghcr.io~1devcontainers~1features~1go:1/version",
+				},
+			},
+			getVer: getVer10,
+			in:     []byte(`{"features":{"ghcr.io/devcontainers/features/go:1":{"version":"1.22"}}}`),
+			expOut: []byte(`{"features":{"ghcr.io/devcontainers/features/go:1":{"version":"10"}}}`),
+		},
+		{
+			name: "no matches",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+				Args: map[string]string{
+					"path": "$.packageRules[*].version",
+				},
+			},
+			getVer: getVerArgs("latest"),
+			in:     []byte("[\n]\n"),
+			expOut: []byte("[\n]\n"),
+		},
+		{
+			name: "sibling fields",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+				Args: map[string]string{
+					"path": "$.deps[*].version",
+				},
+			},
+			getVer: getVerArgs("latest"),
+			in:     []byte(`{"deps": [{"name": "a", "version": "1.0", "latest": "1.1", "nested": {"latest": "x"}}, {"version": "2.0", "latest": 3}]}`),
+			expOut: []byte(`{"deps": [{"name": "a", "version": "1.1", "latest": "1.1", "nested": {"latest": "x"}}, {"version": "3", "latest": 3}]}`),
+		},
+		{
+			name: "path in scan match",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+				Args: map[string]string{
+					"path": "$..ver",
+				},
+			},
+			getVer: getVerArgs("Path"),
+			in:     []byte(`{"a": {"b": [{"ver": "x"}]}}`),
+			expOut: []byte(`{"a": {"b": [{"ver": "$.a.b[0].ver"}]}}`),
+		},
+		{
+			name: "escaped strings",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+				Args: map[string]string{
+					"path": "$['a\"b']",
+				},
+			},
+			getVer: getVerArgs("new"),
+			in:     []byte(`{"a\"b": "1", "new": "<\"2\">"}`),
+			expOut: []byte(`{"a\"b": "<\"2\">", "new": "<\"2\">"}`),
+		},
+		{
+			name: "not a string",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+				Args: map[string]string{
+					"path": "$.version",
+				},
+			},
+			getVer:   getVer10,
+			in:       []byte(`{"version": 1}`),
+			expError: fmt.Errorf("failed to parse json in test: value at $.version is not a string: 1"),
+		},
+		{
+			name: "recursive skips non-strings",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+				Args: map[string]string{
+					"path": "$..version",
+				},
+			},
+			getVer: getVer10,
+			in:     []byte(`{"version": 2, "deps": [{"version": "1.0"}, {"version": null}], "tool": {"version": "2.0", "pinned": {"version": true}}}`),
+			expOut: []byte(`{"version": 2, "deps": [{"version": "10"}, {"version": null}], "tool": {"version": "10", "pinned": {"version": true}}}`),
+		},
+		{
+			name: "wildcard skips non-strings",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+				Args: map[string]string{
+					"path": "$.dependencies.*",
+				},
+			},
+			getVer: getVer10,
+			in:     []byte(`{"dependencies": {"a": "1.0", "b": 2}}`),
+			expOut: []byte(`{"dependencies": {"a": "10", "b": 2}}`),
+		},
+		{
+			name: "invalid json",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "json",
+				Args: map[string]string{
+					"path": "$.version",
+				},
+			},
+			getVer:   getVer10,
+			in:       []byte(`{"version": "1",}`),
+			expError: fmt.Errorf("failed to parse json in test: expected object key at offset 16"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader(tt.in)
+			outBuf := bytes.NewBuffer([]byte{})
+			err := runJSONScan(ctx, tt.confScan, "test", r, outBuf, tt.getVer)
+			if tt.expError != nil {
+				if err == nil {
+					t.Errorf("runJSONScan did not fail")
+				} else if !errors.Is(err, tt.expError) && err.Error() != tt.expError.Error() {
+					t.Errorf("runJSONScan unexpected error, expected %v, received %v", tt.expError, err)
+				}
+				return
+			} else if err != nil {
+				t.Errorf("runJSONScan failed: %v", err)
+				return
+			}
+			out := outBuf.Bytes()
+			if !bytes.Equal(tt.expOut, out) {
+				t.Errorf("result does not match:\n--- expected ---\n%s\n--- received ---\n%s", tt.expOut, out)
+			}
+		})
+	}
+}
@@ -36,10 +36,16 @@ type Scan interface {
 type runScan func(ctx context.Context, conf config.Scan, filename string, r io.Reader, w io.Writer, getVer func(curVer string, args map[string]string) (string, error)) error
 
 var scanTypes map[string]runScan = map[string]runScan{
-	"regexp": runREScan,
+	"regexp":     runREScan,
+	"dockerfile": runDockerfileScan,
+	"json":       runJSONScan,
+	"yaml":       runYAMLScan,
 }
 
 // Run executes the selected scanner.
+// Scanners must read all of r before calling getVer or writing to w.
+// Processors for a file are chained with pipes, and a processor with a lock source relies on
+// the earlier processors resolving every match before its own getVer is called.
 func Run(ctx context.Context, conf config.Scan, filename string, r io.Reader, w io.Writer, getVer func(curVer string, args map[string]string) (string, error)) error {
 	if rs, ok := scanTypes[conf.Type]; ok {
 		return rs(ctx, conf, filename, r, w, getVer)
@@ -0,0 +1,116 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scan
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"sort"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+// valueMatch is a string value found by a scanner of structured files.
+type valueMatch struct {
+	start, end int                 // offsets of the raw value in the file, including any quotes
+	value      string              // decoded value
+	encode     func(string) string // encode a new value to replace the raw value
+	args       map[string]string   // matches to pass to getVer
+}
+
+// valueRegexp compiles the optional regexp used to extract the version from a value.
+func valueRegexp(conf config.Scan) (*regexp.Regexp, error) {
+	expr, ok := conf.Args[regexpArgRE]
+	if !ok || expr == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("scan regexp does not compile for %s: %s: %w", conf.Name, expr, err)
+	}
+	if re.SubexpIndex(regexpVersion) < 0 {
+		return nil, fmt.Errorf("scan regexp is missing Version submatch (i.e. \"(?P<Version>\\d+)\") for %s: %s", conf.Name, expr)
+	}
+	return re, nil
+}
+
+// writeValues calls getVer for each match and outputs the file with any changed values.
+// When re is provided, only the Version submatch of the value is replaced, and values that do not match are skipped.
+// The same value may be matched more than once, but each match must result in the same change.
+func writeValues(b []byte, matches []valueMatch, re *regexp.Regexp, w io.Writer, getVer func(curVer string, args map[string]string) (string, error)) error {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+	lastIndex := 0
+	prevStart, prevEnd, prevVal := -1, -1, ""
+	for _, m := range matches {
+		args := maps.Clone(m.args)
+		if args == nil {
+			args = map[string]string{}
+		}
+		verStart, verEnd := 0, len(m.value)
+		if re != nil {
+			idx := re.FindStringSubmatchIndex(m.value)
+			if idx == nil {
+				continue
+			}
+			for i, name := range re.SubexpNames() {
+				if name != "" && idx[i*2] >= 0 {
+					args[name] = m.value[idx[i*2]:idx[i*2+1]]
+				}
+			}
+			verI := re.SubexpIndex(regexpVersion) * 2
+			if idx[verI] < 0 {
+				continue
+			}
+			verStart, verEnd = idx[verI], idx[verI+1]
+		}
+		curVer := m.value[verStart:verEnd]
+		args[regexpVersion] = curVer
+		newVer, err := getVer(curVer, args)
+		if err != nil {
+			return err
+		}
+		newVal := m.value[:verStart] + newVer + m.value[verEnd:]
+		if m.start == prevStart && m.end == prevEnd {
+			if newVal != prevVal {
+				return fmt.Errorf("conflicting changes to the value at offset %d: %s and %s", m.start, prevVal, newVal)
+			}
+			continue
+		}
+		prevStart, prevEnd, prevVal = m.start, m.end, newVal
+		if newVer == curVer {
+			continue
+		}
+		if m.start < lastIndex {
+			return fmt.Errorf("value matches overlap at offset %d", m.start)
+		}
+		if _, err := w.Write(b[lastIndex:m.start]); err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(m.encode(newVal))); err != nil {
+			return err
+		}
+		lastIndex = m.end
+	}
+	if lastIndex < len(b) {
+		if _, err := w.Write(b[lastIndex:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+	"github.com/sudo-bmitch/version-bump/internal/datapath"
+)
+
+const (
+	yamlArgPath = "path"
+	yamlPath    = "Path"
+)
+
+var yamlDoubleQuoteReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+// runYAMLScan executes a scanner on a YAML file, selecting scalar values with a path.
+func runYAMLScan(ctx context.Context, conf config.Scan, filename string, r io.Reader, w io.Writer, getVer func(curVer string, args map[string]string) (string, error)) error {
+	if _, ok := conf.Args[yamlArgPath]; !ok {
+		return fmt.Errorf("scan path arg is missing for %s", conf.Name)
+	}
+	path, err := datapath.Parse(conf.Args[yamlArgPath])
+	if err != nil {
+		return fmt.Errorf("scan path is invalid for %s: %w", conf.Name, err)
+	}
+	re, err := valueRegexp(conf)
+	if err != nil {
+		return err
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	f, err := parser.ParseBytes(b, 0)
+	if err != nil {
+		return fmt.Errorf("failed to parse yaml in %s: %w", filename, err)
+	}
+	ys := yamlScan{
+		src:     b,
+		lines:   lineOffsets(b),
+		path:    path,
+		matches: []valueMatch{},
+	}
+	for _, doc := range f.Docs {
+		if err := ys.walk(doc.Body, datapath.Steps{}, nil); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", filename, err)
+		}
+	}
+	return writeValues(b, ys.matches, re, w, getVer)
+}
+
+type yamlScan struct {
+	src     []byte
+	lines   []int // offset to the start of each line
+	path    *datapath.Path
+	matches []valueMatch
+}
+
+// walk searches the node for scalars matching the path.
+// The parent is the list of values in the containing mapping, used to include sibling fields in the scan match.
+func (ys *yamlScan) walk(node ast.Node, steps datapath.Steps, parent []*ast.MappingValueNode) error {
+	switch n := node.(type) {
+	case nil:
+		return nil
+	case *ast.DocumentNode:
+		return ys.walk(n.Body, steps, nil)
+	case *ast.MappingNode:
+		for _, mv := range n.Values {
+			if err := ys.walkPair(mv, steps, n.Values); err != nil {
+				return err
+			}
+		}
+	case *ast.MappingValueNode:
+		return ys.walkPair(n, steps, []*ast.MappingValueNode{n})
+	case *ast.SequenceNode:
+		for i, v := range n.Values {
+			if err := ys.walk(v, steps.Append(datapath.IndexStep(i)), nil); err != nil {
+				return err
+			}
+		}
+	case *ast.AnchorNode:
+		return ys.walk(n.Value, steps, parent)
+	case *ast.TagNode:
+		return ys.walk(n.Value, steps, parent)
+	case *ast.AliasNode, *ast.NullNode:
+		// aliases are updated at the anchor
+		return nil
+	case *ast.LiteralNode:
+		if ys.path.Match(steps) {
+			return fmt.Errorf("block scalars are not supported at %s", steps)
+		}
+	case ast.ScalarNode:
+		if ys.path.Match(steps) {
+			return ys.addScalar(n.GetToken(), steps, parent)
+		}
+	}
+	return nil
+}
+
+func (ys *yamlScan) walkPair(mv *ast.MappingValueNode, steps datapath.Steps, parent []*ast.MappingValueNode) error {
+	// merged values are updated where they are defined
+	if mv.Key == nil || mv.Key.IsMergeKey() {
+		return nil
+	}
+	key, ok := yamlScalar(mv.Key)
+	if !ok {
+		return nil
+	}
+	return ys.walk(mv.Value, steps.Append(datapath.KeyStep(key)), parent)
+}
+
+// addScalar locates the raw value in the source to track the match.
+func (ys *yamlScan) addScalar(tok *token.Token, steps datapath.Steps, parent []*ast.MappingValueNode) error {
+	start, err := ys.offset(tok.Position)
+	if err != nil {
+		return fmt.Errorf("failed to locate value at %s: %w", steps, err)
+	}
+	m := valueMatch{
+		start: start,
+		value: tok.Value,
+		args:  map[string]string{},
+	}
+	switch tok.Type {
+	case token.DoubleQuoteType:
+		m.end = quoteEnd(ys.src, start, '"', '\\')
+		m.encode = func(s string) string {
+			return `"` + yamlDoubleQuoteReplacer.Replace(s) + `"`
+		}
+	case token.SingleQuoteType:
+		m.end = quoteEnd(ys.src, start, '\'', '\'')
+		m.encode = func(s string) string {
+			return `'` + strings.ReplaceAll(s, `'`, `''`) + `'`
+		}
+	default:
+		if !bytes.HasPrefix(ys.src[start:], []byte(tok.Value)) {
+			return fmt.Errorf("multi-line values are not supported at %s", steps)
+		}
+		m.end = start + len(tok.Value)
+		m.encode = func(s string) string {
+			return s
+		}
+	}
+	if m.end < 0 || bytes.IndexByte(ys.src[start:m.end], '\n') >= 0 {
+		return fmt.Errorf("multi-line values are not supported at %s", steps)
+	}
+	// include sibling scalar fields
+	for _, mv := range parent {
+		if mv.Key == nil || mv.Key.IsMergeKey() {
+			continue
+		}
+		k, okK := yamlScalar(mv.Key)
+		v, okV := yamlScalar(mv.Value)
+		if okK && okV {
+			m.args[k] = v
+		}
+	}
+	m.args[yamlPath] = steps.String()
+	ys.matches = append(ys.matches, m)
+	return nil
+}
+
+// offset converts the line and column of a token to the offset in the source.
+func (ys *yamlScan) offset(pos *token.Position) (int, error) {
+	if pos == nil || pos.Line < 1 || pos.Line > len(ys.lines) || pos.Column < 1 {
+		return 0, fmt.Errorf("invalid position")
+	}
+	// columns count characters, not bytes
+	offset := ys.lines[pos.Line-1]
+	for range pos.Column - 1 {
+		if offset >= len(ys.src) || ys.src[offset] == '\n' {
+			return 0, fmt.Errorf("column %d is beyond the end of line %d", pos.Column, pos.Line)
+		}
+		_, size := utf8.DecodeRune(ys.src[offset:])
+		offset += size
+	}
+	return offset, nil
+}
+
+// yamlScalar returns the value of a scalar node, unwrapping any anchors and tags.
+func yamlScalar(node ast.Node) (string, bool) {
+	switch n := node.(type) {
+	case *ast.AnchorNode:
+		return yamlScalar(n.Value)
+	case *ast.TagNode:
+		return yamlScalar(n.Value)
+	case *ast.MappingKeyNode:
+		return yamlScalar(n.Value)
+	case *ast.LiteralNode, *ast.NullNode:
+		return "", false
+	case ast.ScalarNode:
+		return n.GetToken().Value, true
+	}
+	return "", false
+}
+
+// lineOffsets returns the offset to the start of each line.
+func lineOffsets(b []byte) []int {
+	lines := []int{0}
+	for i, c := range b {
+		if c == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
+
+// quoteEnd returns the offset after the closing quote for a string starting at start, or -1 if not found.
+// The escape character may be the same as the quote, where a doubled quote is an escaped quote.
+func quoteEnd(b []byte, start int, quote, escape byte) int {
+	if start >= len(b) || b[start] != quote {
+		return -1
+	}
+	for i := start + 1; i < len(b); i++ {
+		switch {
+		case b[i] == escape && escape != quote:
+			i++
+		case b[i] == quote && escape == quote && i+1 < len(b) && b[i+1] == quote:
+			i++
+		case b[i] == quote:
+			return i + 1
+		}
+	}
+	return -1
+}
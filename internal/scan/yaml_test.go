@@ -0,0 +1,221 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+// getVerArgs returns the version from the args map using the key, e.g. a sibling field from the scan.
+func getVerArgs(key string) func(curVer string, args map[string]string) (string, error) {
+	return func(curVer string, args map[string]string) (string, error) {
+		if v, ok := args[key]; ok {
+			return v, nil
+		}
+		return curVer, nil
+	}
+}
+
+func TestYAML(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		confScan config.Scan
+		getVer   func(curVer string, args map[string]string) (string, error)
+		in       []byte
+		expError error
+		expOut   []byte
+	}{
+		{
+			name: "missing path",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "yaml",
+			},
+			getVer:   getVer10,
+			in:       []byte("a: 1\n"),
+			expError: fmt.Errorf("scan path arg is missing for test"),
+		},
+		{
+			name: "plain value",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "yaml",
+				Args: map[string]string{
+					"path": "$.version",
+				},
+			},
+			getVer: getVer10,
+			in:     []byte("# leading comment\nname: test\nversion: 1.20 # trailing comment\n"),
+			expOut: []byte("# leading comment\nname: test\nversion: 10 # trailing comment\n"),
+		},
+		{
+			name: "containers with regexp",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "yaml",
+				Args: map[string]string{
+					"path":   "spec.template.spec.containers[*].image",
+					"regexp": `^(?P<Image>[^:@]+):(?P<Version>[^@]+)$`,
+				},
+			},
+			getVer: getVer10,
+			in: []byte(`apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: "registry.example.com/app:1.2.3"  # app
+        - name: sidecar
+          image: 'proxy:4.5'
+        - name: pinned
+          image: proxy@sha256:1234
+`),
+			expOut: []byte(`apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: "registry.example.com/app:10"  # app
+        - name: sidecar
+          image: 'proxy:10'
+        - name: pinned
+          image: proxy@sha256:1234
+`),
+		},
+		{
+			name: "sibling fields",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "yaml",
+				Args: map[string]string{
+					"path": "$.deps[*].version",
+				},
+			},
+			getVer: getVerArgs("latest"),
+			in: []byte(`deps:
+  - {name: a, version: "1.0", latest: "1.1"}
+  - name: b
+    version: 2.0
+    latest: 2.5
+`),
+			expOut: []byte(`deps:
+  - {name: a, version: "1.1", latest: "1.1"}
+  - name: b
+    version: 2.5
+    latest: 2.5
+`),
+		},
+		{
+			name: "path in scan match",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "yaml",
+				Args: map[string]string{
+					"path": "$..ver",
+				},
+			},
+			getVer: getVerArgs("Path"),
+			in:     []byte("a:\n  b:\n    - ver: x\n"),
+			expOut: []byte("a:\n  b:\n    - ver: $.a.b[0].ver\n"),
+		},
+		{
+			name: "anchors and aliases",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "yaml",
+				Args: map[string]string{
+					"path": "$..version",
+				},
+			},
+			getVer: getVer10,
+			in:     []byte("base: &base\n  version: &ver 1.0\nother:\n  <<: *base\n  version: *ver\n"),
+			expOut: []byte("base: &base\n  version: &ver 10\nother:\n  <<: *base\n  version: *ver\n"),
+		},
+		{
+			name: "multiple documents",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "yaml",
+				Args: map[string]string{
+					"path": "$.version",
+				},
+			},
+			getVer: getVer10,
+			in:     []byte("version: 1\n---\nversion: '2'\n"),
+			expOut: []byte("version: 10\n---\nversion: '10'\n"),
+		},
+		{
+			name: "unicode",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "yaml",
+				Args: map[string]string{
+					"path": "$['café']",
+				},
+			},
+			getVer: getVer10,
+			in:     []byte("café: \"1\"\n"),
+			expOut: []byte("café: \"10\"\n"),
+		},
+		{
+			name: "block scalar",
+			confScan: config.Scan{
+				Name: "test",
+				Type: "yaml",
+				Args: map[string]string{
+					"path": "$.version",
+				},
+			},
+			getVer:   getVer10,
+			in:       []byte("version: |\n  1.0\n"),
+			expError: fmt.Errorf("failed to scan test: block scalars are not supported at $.version"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader(tt.in)
+			outBuf := bytes.NewBuffer([]byte{})
+			err := runYAMLScan(ctx, tt.confScan, "test", r, outBuf, tt.getVer)
+			if tt.expError != nil {
+				if err == nil {
+					t.Errorf("runYAMLScan did not fail")
+				} else if !errors.Is(err, tt.expError) && err.Error() != tt.expError.Error() {
+					t.Errorf("runYAMLScan unexpected error, expected %v, received %v", tt.expError, err)
+				}
+				return
+			} else if err != nil {
+				t.Errorf("runYAMLScan failed: %v", err)
+				return
+			}
+			out := outBuf.Bytes()
+			if !bytes.Equal(tt.expOut, out) {
+				t.Errorf("result does not match:\n--- expected ---\n%s\n--- received ---\n%s", tt.expOut, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+const cacheExt = ".cache"
+
+// CacheOpts configures the persistent cache of source results.
+type CacheOpts struct {
+	Dir     string        // Dir is the directory for cache files, the cache is disabled when empty
+	TTL     time.Duration // TTL is the default time to keep results, sources may override this with CacheTTL
+	Refresh bool          // Refresh skips reading from the cache, new results are still saved
+}
+
+var cacheState struct {
+	mu      sync.Mutex
+	opts    CacheOpts
+	pending map[string]cachePending // results with metadata that may be loaded after the entry was saved, by cache file
+}
+
+// cachePending is an entry to save again with [CacheFlush].
+type cachePending struct {
+	src     config.Source
+	created time.Time
+	res     Results
+}
+
+// cacheLazy is implemented by VerMeta values that load fields when first used.
+// The loaded fields are included with GobEncode, and cacheRestore provides the source config after reading from the cache.
+type cacheLazy interface {
+	cacheRestore(src config.Source)
+}
+
+// cacheSkip lists source types that depend on local state, and are only cached when a CacheTTL is set on the source.
+var cacheSkip = map[string]bool{
+	"custom":    true,
+	"file":      true,
+	"git-local": true,
+	"manual":    true,
+}
+
+// cacheEntry is the content of each cache file.
+// Args may include credentials (e.g. url headers), so only the hash of the type and args is stored.
+type cacheEntry struct {
+	Type    string
+	Key     string
+	Created time.Time
+	Results Results
+}
+
+func init() {
+	// types included in VerMeta must be registered to be cached
+	// each source registers its own types, these are the generic types used by multiple sources
+	gob.Register(map[string]any{})
+	gob.Register(map[string]string{})
+	gob.Register([]any{})
+	gob.Register(json.Number(""))
+}
+
+// CacheSetup configures the persistent cache used by Get.
+func CacheSetup(opts CacheOpts) {
+	cacheState.mu.Lock()
+	defer cacheState.mu.Unlock()
+	cacheState.opts = opts
+}
+
+// CacheFlush saves the results from this run again to include metadata that was loaded after the results were cached.
+// Only results with metadata that is loaded when first used are saved.
+func CacheFlush() error {
+	cacheState.mu.Lock()
+	pending := cacheState.pending
+	cacheState.pending = nil
+	cacheState.mu.Unlock()
+	errs := []error{}
+	for _, p := range pending {
+		if err := cacheWrite(p.src, p.res, p.created); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CacheClear deletes all cached results from the directory.
+func CacheClear(dir string) error {
+	if dir == "" {
+		return fmt.Errorf("cache directory is not defined")
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*"+cacheExt))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to delete %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// cacheTTL returns the time to keep results for a source, or 0 if the results should not be cached.
+func cacheTTL(src config.Source) (time.Duration, error) {
+	cacheState.mu.Lock()
+	opts := cacheState.opts
+	cacheState.mu.Unlock()
+	if opts.Dir == "" {
+		return 0, nil
+	}
+	if src.CacheTTL != "" {
+		ttl, err := time.ParseDuration(src.CacheTTL)
+		if err != nil {
+			return 0, fmt.Errorf("invalid cacheTTL for source %s: %w", src.Name, err)
+		}
+		return max(ttl, 0), nil
+	}
+	if cacheSkip[src.Type] {
+		return 0, nil
+	}
+	return max(opts.TTL, 0), nil
+}
+
+// cacheKey returns a hash of the type and args for a source.
+func cacheKey(src config.Source) (string, error) {
+	// json sorts the map keys for a consistent hash
+	b, err := json.Marshal(struct {
+		Type string
+		Args map[string]string
+	}{Type: src.Type, Args: src.Args})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cache key for source %s: %w", src.Name, err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cacheFile returns the filename for a source, based on the type and the hash of the args.
+func cacheFile(dir string, src config.Source, key string) string {
+	return filepath.Join(dir, src.Type+"-"+key+cacheExt)
+}
+
+// cacheGet returns the cached results for a source if they have not expired.
+func cacheGet(src config.Source, ttl time.Duration) (Results, bool) {
+	cacheState.mu.Lock()
+	opts := cacheState.opts
+	cacheState.mu.Unlock()
+	if opts.Refresh {
+		return Results{}, false
+	}
+	key, err := cacheKey(src)
+	if err != nil {
+		return Results{}, false
+	}
+	fh, err := os.Open(cacheFile(opts.Dir, src, key))
+	if err != nil {
+		return Results{}, false
+	}
+	defer fh.Close()
+	entry := cacheEntry{}
+	if err := gob.NewDecoder(fh).Decode(&entry); err != nil {
+		return Results{}, false
+	}
+	if entry.Type != src.Type || entry.Key != key || time.Since(entry.Created) > ttl {
+		return Results{}, false
+	}
+	lazy := false
+	for _, meta := range entry.Results.VerMeta {
+		if l, ok := meta.(cacheLazy); ok {
+			l.cacheRestore(src)
+			lazy = true
+		}
+	}
+	if lazy {
+		cacheAddPending(src, entry.Results, entry.Created)
+	}
+	return entry.Results, true
+}
+
+// cacheAddPending tracks results with lazily loaded metadata to save again with [CacheFlush].
+func cacheAddPending(src config.Source, res Results, created time.Time) {
+	cacheState.mu.Lock()
+	defer cacheState.mu.Unlock()
+	key, err := cacheKey(src)
+	if err != nil {
+		return
+	}
+	if cacheState.pending == nil {
+		cacheState.pending = map[string]cachePending{}
+	}
+	cacheState.pending[key] = cachePending{src: src, created: created, res: res}
+}
+
+// cachePut saves the results for a source.
+func cachePut(src config.Source, res Results) error {
+	created := time.Now()
+	for _, meta := range res.VerMeta {
+		if _, ok := meta.(cacheLazy); ok {
+			cacheAddPending(src, res, created)
+			break
+		}
+	}
+	return cacheWrite(src, res, created)
+}
+
+// cacheWrite saves the results for a source to the cache file.
+func cacheWrite(src config.Source, res Results, created time.Time) error {
+	cacheState.mu.Lock()
+	dir := cacheState.opts.Dir
+	cacheState.mu.Unlock()
+	key, err := cacheKey(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	// write to a temp file and rename to avoid partial entries
+	fh, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := fh.Name()
+	err = gob.NewEncoder(fh).Encode(cacheEntry{
+		Type:    src.Type,
+		Key:     key,
+		Created: created,
+		Results: res,
+	})
+	errC := fh.Close()
+	if err == nil {
+		err = errC
+	}
+	if err == nil {
+		err = os.Rename(tmpName, cacheFile(dir, src, key))
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// gobEncode is used by the GobEncode method of types with unexported fields to cache.
+func gobEncode(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	err := gob.NewEncoder(buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+// gobDecode is used by the GobDecode method of types with unexported fields to cache.
+func gobDecode(b []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
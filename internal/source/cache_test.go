@@ -0,0 +1,240 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	count := 0
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	sourceTypes["test-count"] = func(conf config.Source) (Results, error) {
+		count++
+		return Results{
+			VerMap: map[string]string{
+				"v1": conf.Args["value"],
+			},
+			VerMeta: map[string]any{
+				"v1": &GHRelease{TagName: "v1", PublishedAt: GHTime(published)},
+			},
+		}, nil
+	}
+	t.Cleanup(func() {
+		delete(sourceTypes, "test-count")
+		CacheSetup(CacheOpts{})
+	})
+	src := config.Source{
+		Name: "test",
+		Type: "test-count",
+		Args: map[string]string{
+			"value": "a",
+		},
+	}
+	get := func(t *testing.T, src config.Source, expCount int) {
+		t.Helper()
+		res, err := Get(src)
+		if err != nil {
+			t.Fatalf("failed to get: %v", err)
+		}
+		if res.VerMap["v1"] != src.Args["value"] {
+			t.Errorf("unexpected result, expected %s, received %s", src.Args["value"], res.VerMap["v1"])
+		}
+		if rel, ok := res.VerMeta["v1"].(*GHRelease); !ok || !time.Time(rel.PublishedAt).Equal(published) {
+			t.Errorf("unexpected metadata: %#v", res.VerMeta["v1"])
+		}
+		if count != expCount {
+			t.Errorf("unexpected number of source queries, expected %d, received %d", expCount, count)
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		CacheSetup(CacheOpts{TTL: time.Hour})
+		get(t, src, 1)
+		get(t, src, 2)
+	})
+	t.Run("cached", func(t *testing.T) {
+		CacheSetup(CacheOpts{Dir: dir, TTL: time.Hour})
+		get(t, src, 3)
+		get(t, src, 3)
+		// changing the args is a different entry
+		src2 := src.Clone()
+		src2.Args["value"] = "b"
+		get(t, src2, 4)
+		get(t, src2, 4)
+		get(t, src, 4)
+	})
+	t.Run("refresh", func(t *testing.T) {
+		CacheSetup(CacheOpts{Dir: dir, TTL: time.Hour, Refresh: true})
+		get(t, src, 5)
+		CacheSetup(CacheOpts{Dir: dir, TTL: time.Hour})
+		get(t, src, 5)
+	})
+	t.Run("expired", func(t *testing.T) {
+		CacheSetup(CacheOpts{Dir: dir, TTL: time.Nanosecond})
+		get(t, src, 6)
+	})
+	t.Run("source ttl", func(t *testing.T) {
+		CacheSetup(CacheOpts{Dir: dir, TTL: time.Nanosecond})
+		srcTTL := src.Clone()
+		srcTTL.CacheTTL = "1h"
+		get(t, srcTTL, 6)
+		srcTTL.CacheTTL = "0"
+		CacheSetup(CacheOpts{Dir: dir, TTL: time.Hour})
+		get(t, srcTTL, 7)
+		srcTTL.CacheTTL = "invalid"
+		if _, err := Get(srcTTL); err == nil {
+			t.Errorf("invalid cacheTTL did not fail")
+		}
+	})
+	t.Run("skipped types", func(t *testing.T) {
+		CacheSetup(CacheOpts{Dir: dir, TTL: time.Hour})
+		manual := config.Source{
+			Name: "manual",
+			Type: "manual",
+			Args: map[string]string{
+				"Version": "1.2.3",
+			},
+		}
+		if _, err := Get(manual); err != nil {
+			t.Fatalf("failed to get: %v", err)
+		}
+		if _, err := cacheGet(manual, time.Hour); err {
+			t.Errorf("manual source was cached")
+		}
+	})
+	t.Run("clear", func(t *testing.T) {
+		files, _ := filepath.Glob(filepath.Join(dir, "*"+cacheExt))
+		if len(files) != 2 {
+			t.Errorf("unexpected cache files: %v", files)
+		}
+		if err := CacheClear(dir); err != nil {
+			t.Fatalf("failed to clear cache: %v", err)
+		}
+		files, _ = filepath.Glob(filepath.Join(dir, "*"+cacheExt))
+		if len(files) != 0 {
+			t.Errorf("cache files remain after clear: %v", files)
+		}
+		CacheSetup(CacheOpts{Dir: dir, TTL: time.Hour})
+		get(t, src, 8)
+		if err := CacheClear(""); err == nil {
+			t.Errorf("clear without a directory did not fail")
+		}
+	})
+	t.Run("args not stored", func(t *testing.T) {
+		CacheSetup(CacheOpts{Dir: dir, TTL: time.Hour})
+		srcAuth := src.Clone()
+		srcAuth.Args["header.Authorization"] = "Bearer secret-token"
+		get(t, srcAuth, 9)
+		get(t, srcAuth, 9)
+		files, _ := filepath.Glob(filepath.Join(dir, "*"+cacheExt))
+		for _, file := range files {
+			b, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", file, err)
+			}
+			if bytes.Contains(b, []byte("secret-token")) {
+				t.Errorf("cache file contains the args: %s", file)
+			}
+		}
+	})
+}
+
+func TestCacheLazy(t *testing.T) {
+	CacheSetup(CacheOpts{Dir: t.TempDir(), TTL: time.Hour})
+	t.Cleanup(func() { CacheSetup(CacheOpts{}) })
+	committed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	src := config.Source{
+		Name: "lazy",
+		Type: "test-lazy",
+	}
+	// the urls are invalid, so any request to load the metadata fails
+	res := Results{
+		VerMap: map[string]string{
+			"loaded":   "loaded",
+			"unloaded": "unloaded",
+			"tag":      "tag",
+		},
+		VerMeta: map[string]any{
+			"loaded": &GitRef{
+				URL:    "invalid://example.com/repo.git",
+				Name:   "loaded",
+				loaded: true,
+				details: gitRefDetails{
+					commitDate: committed,
+					author:     "Author <author@example.com>",
+				},
+			},
+			"unloaded": &GitRef{
+				URL:  "invalid://example.com/repo.git",
+				Name: "unloaded",
+			},
+			"tag": &RegTag{
+				Repo:   "invalid//repo",
+				Tag:    "tag",
+				digest: "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			},
+		},
+	}
+	if err := cachePut(src, res); err != nil {
+		t.Fatalf("failed to cache results: %v", err)
+	}
+	cached, ok := cacheGet(src, time.Hour)
+	if !ok {
+		t.Fatalf("cached results not found")
+	}
+	ref, ok := cached.VerMeta["loaded"].(*GitRef)
+	if !ok || ref.Name != "loaded" {
+		t.Fatalf("unexpected metadata: %#v", cached.VerMeta["loaded"])
+	}
+	if date, err := ref.CommitDate(); err != nil || !date.Equal(committed) {
+		t.Errorf("unexpected commit date: %v, %v", date, err)
+	}
+	if author, err := ref.Author(); err != nil || author != "Author <author@example.com>" {
+		t.Errorf("unexpected author: %s, %v", author, err)
+	}
+	ref, ok = cached.VerMeta["unloaded"].(*GitRef)
+	if !ok || ref.Name != "unloaded" {
+		t.Fatalf("unexpected metadata: %#v", cached.VerMeta["unloaded"])
+	}
+	if _, err := ref.CommitDate(); err == nil {
+		t.Errorf("details were not loaded from the url")
+	}
+	tag, ok := cached.VerMeta["tag"].(*RegTag)
+	if !ok || tag.Tag != "tag" {
+		t.Fatalf("unexpected metadata: %#v", cached.VerMeta["tag"])
+	}
+	if digest, err := tag.Digest(); err != nil || digest != res.VerMeta["tag"].(*RegTag).digest {
+		t.Errorf("unexpected digest: %s, %v", digest, err)
+	}
+	// a failed load is not saved to the cache
+	if err := CacheFlush(); err != nil {
+		t.Fatalf("failed to flush cache: %v", err)
+	}
+	cached, ok = cacheGet(src, time.Hour)
+	if !ok {
+		t.Fatalf("cached results not found after flush")
+	}
+	if ref, ok := cached.VerMeta["unloaded"].(*GitRef); !ok || ref.loaded {
+		t.Errorf("unexpected metadata after flush: %#v", cached.VerMeta["unloaded"])
+	}
+}
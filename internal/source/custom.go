@@ -15,31 +15,214 @@
 package source
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/sudo-bmitch/version-bump/internal/config"
 )
 
 const (
-	customCmd = "cmd"
+	customCmd          = "cmd"
+	customExec         = "exec"
+	customExecPrefix   = "exec."
+	customDir          = "dir"
+	customEnvPrefix    = "env."
+	customOutput       = "output"
+	customJSONKey      = "jsonKey"
+	customOutputSingle = "single"
+	customOutputLines  = "lines"
+	customOutputKV     = "kv"
+	customOutputJSON   = "json"
+	customDefJSONKey   = "version"
 )
 
+// newCustom runs a command and parses the output into versions.
+// The cmd arg is run with /bin/sh, while exec runs a command without a shell.
+// The exec command and args are either a JSON array in the exec arg, e.g. exec: '["git", "describe", "--tags"]',
+// or indexed args starting from zero, e.g. exec.0: git, exec.1: describe, exec.2: --tags.
+// The output may be single for the entire output (default), lines for a version per line, kv for key=value lines,
+// or json for an object of keys to values, or an array of versions or objects with a jsonKey field (default "version").
 func newCustom(src config.Source) (Results, error) {
-	// TODO: add support for exec, bypassing the shell, which means arg values need to also support arrays
-	if _, ok := src.Args[customCmd]; !ok {
-		return Results{}, fmt.Errorf("custom source requires a cmd arg")
+	var cmd *exec.Cmd
+	name := src.Args[customCmd]
+	_, hasCmd := src.Args[customCmd]
+	argv, hasExec, err := customArgv(src.Args)
+	if err != nil {
+		return Results{}, err
+	}
+	switch {
+	case hasCmd && hasExec:
+		return Results{}, fmt.Errorf("custom source cannot have both a cmd and exec arg")
+	case hasCmd:
+		//#nosec G204 command to run is controlled by user running the command
+		cmd = exec.Command("/bin/sh", "-c", src.Args[customCmd])
+	case hasExec:
+		if len(argv) == 0 || argv[0] == "" {
+			return Results{}, fmt.Errorf("exec must include a command to run")
+		}
+		name = strings.Join(argv, " ")
+		//#nosec G204 command to run is controlled by user running the command
+		cmd = exec.Command(argv[0], argv[1:]...)
+	default:
+		return Results{}, fmt.Errorf("custom source requires a cmd or exec arg")
 	}
-	//#nosec G204 command to run is controlled by user running the command
-	out, err := exec.Command("/bin/sh", "-c", src.Args[customCmd]).Output()
+	cmd.Dir = src.Args[customDir]
+	for k, v := range src.Args {
+		if envName, ok := strings.CutPrefix(k, customEnvPrefix); ok && envName != "" {
+			if cmd.Env == nil {
+				cmd.Env = os.Environ()
+			}
+			cmd.Env = append(cmd.Env, envName+"="+v)
+		}
+	}
+	out, err := cmd.Output()
 	if err != nil {
-		return Results{}, fmt.Errorf("failed running %s: %w", src.Args[customCmd], err)
-	}
-	outVer := strings.TrimSpace(string(out))
-	return Results{
-		VerMap: map[string]string{
-			outVer: outVer,
-		},
-	}, nil
+		var ee *exec.ExitError
+		if errors.As(err, &ee) && len(ee.Stderr) > 0 {
+			return Results{}, fmt.Errorf("failed running %s: %w: %s", name, err, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return Results{}, fmt.Errorf("failed running %s: %w", name, err)
+	}
+	switch src.Args[customOutput] {
+	case "", customOutputSingle:
+		outVer := strings.TrimSpace(string(out))
+		return Results{
+			VerMap: map[string]string{
+				outVer: outVer,
+			},
+		}, nil
+	case customOutputLines, customOutputKV:
+		res := Results{
+			VerMap: map[string]string{},
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			if src.Args[customOutput] == customOutputLines {
+				res.VerMap[line] = line
+				continue
+			}
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			k, v, ok := strings.Cut(line, "=")
+			if !ok {
+				return Results{}, fmt.Errorf("output of %s is not a key=value line: %s", name, line)
+			}
+			res.VerMap[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		if len(res.VerMap) == 0 {
+			return Results{}, fmt.Errorf("no versions found in the output of %s", name)
+		}
+		return res, nil
+	case customOutputJSON:
+		jsonKey := src.Args[customJSONKey]
+		if jsonKey == "" {
+			jsonKey = customDefJSONKey
+		}
+		return customJSON(out, name, jsonKey)
+	default:
+		return Results{}, fmt.Errorf("unsupported output: %s", src.Args[customOutput])
+	}
+}
+
+// customArgv returns the command and args from the exec arg, or from the indexed exec.0, exec.1, ... args.
+// The bool is false when no exec args are defined.
+func customArgv(args map[string]string) ([]string, bool, error) {
+	indexed := map[int]string{}
+	for k, v := range args {
+		idxStr, ok := strings.CutPrefix(k, customExecPrefix)
+		if !ok {
+			continue
+		}
+		idx, err := strconv.Atoi(idxStr)
+		if err != nil || idx < 0 || strconv.Itoa(idx) != idxStr {
+			return nil, false, fmt.Errorf("exec arg index must be a number: %s", k)
+		}
+		indexed[idx] = v
+	}
+	list, hasList := args[customExec]
+	switch {
+	case hasList && len(indexed) > 0:
+		return nil, false, fmt.Errorf("custom source cannot have both an exec arg and indexed exec args")
+	case hasList:
+		argv := []string{}
+		if err := json.Unmarshal([]byte(list), &argv); err != nil {
+			return nil, false, fmt.Errorf("exec must be a JSON array of strings: %s: %w", list, err)
+		}
+		return argv, true, nil
+	case len(indexed) > 0:
+		argv := make([]string, len(indexed))
+		for i := range argv {
+			v, ok := indexed[i]
+			if !ok {
+				return nil, false, fmt.Errorf("exec args must be numbered from 0 without gaps, missing %s%d", customExecPrefix, i)
+			}
+			argv[i] = v
+		}
+		return argv, true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// customJSON parses the JSON output of a command.
+// Objects map each key to a scalar value, or to itself when the value is not a scalar, with the value in the metadata.
+// Arrays map each scalar value to itself, and each object by the jsonKey field, with the object in the metadata.
+func customJSON(out []byte, name, jsonKey string) (Results, error) {
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(out))
+	// numbers are preserved as strings, e.g. 1.10 is not converted to 1.1
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return Results{}, fmt.Errorf("failed to parse json output of %s: %w", name, err)
+	}
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	switch doc := doc.(type) {
+	case map[string]any:
+		for k, v := range doc {
+			if ver, ok := urlScalar(v); ok {
+				res.VerMap[k] = ver
+			} else {
+				res.VerMap[k] = k
+			}
+			if v != nil {
+				res.VerMeta[k] = v
+			}
+		}
+	case []any:
+		for _, v := range doc {
+			if ver, ok := urlScalar(v); ok {
+				res.VerMap[ver] = ver
+				continue
+			}
+			obj, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			ver, ok := urlScalar(obj[jsonKey])
+			if !ok || ver == "" {
+				continue
+			}
+			res.VerMap[ver] = ver
+			res.VerMeta[ver] = obj
+		}
+	default:
+		return Results{}, fmt.Errorf("json output of %s must be an object or array", name)
+	}
+	if len(res.VerMap) == 0 {
+		return Results{}, fmt.Errorf("no versions found in the output of %s", name)
+	}
+	return res, nil
 }
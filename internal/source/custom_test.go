@@ -0,0 +1,218 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestCustom(t *testing.T) {
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to resolve temp dir: %v", err)
+	}
+	tests := []struct {
+		name    string
+		args    map[string]string
+		expErr  bool
+		expMap  map[string]string
+		expMeta map[string]any
+	}{
+		{
+			name:   "missing cmd",
+			args:   map[string]string{},
+			expErr: true,
+		},
+		{
+			name:   "cmd and exec",
+			args:   map[string]string{"cmd": "echo 1.2.3", "exec": `["echo", "1.2.3"]`},
+			expErr: true,
+		},
+		{
+			name:   "exec invalid",
+			args:   map[string]string{"exec": "echo 1.2.3"},
+			expErr: true,
+		},
+		{
+			name:   "exec empty",
+			args:   map[string]string{"exec": "[]"},
+			expErr: true,
+		},
+		{
+			name:   "exec failure",
+			args:   map[string]string{"exec": `["false"]`},
+			expErr: true,
+		},
+		{
+			name:   "unsupported output",
+			args:   map[string]string{"exec": `["echo", "1.2.3"]`, "output": "xml"},
+			expErr: true,
+		},
+		{
+			name:   "exec single",
+			args:   map[string]string{"exec": `["echo", "1.2.3 $HOME"]`},
+			expMap: map[string]string{"1.2.3 $HOME": "1.2.3 $HOME"},
+		},
+		{
+			name:   "exec indexed",
+			args:   map[string]string{"exec.0": "echo", "exec.1": "1.2.3", "exec.2": "$HOME"},
+			expMap: map[string]string{"1.2.3 $HOME": "1.2.3 $HOME"},
+		},
+		{
+			name:   "exec indexed gap",
+			args:   map[string]string{"exec.0": "echo", "exec.2": "1.2.3"},
+			expErr: true,
+		},
+		{
+			name:   "exec indexed invalid",
+			args:   map[string]string{"exec.0": "echo", "exec.one": "1.2.3"},
+			expErr: true,
+		},
+		{
+			name:   "exec and indexed",
+			args:   map[string]string{"exec": `["echo", "1.2.3"]`, "exec.0": "echo"},
+			expErr: true,
+		},
+		{
+			name:   "cmd and indexed",
+			args:   map[string]string{"cmd": "echo 1.2.3", "exec.0": "echo"},
+			expErr: true,
+		},
+		{
+			name:   "cmd env",
+			args:   map[string]string{"cmd": "echo $TEST_VER", "env.TEST_VER": "2.0.0"},
+			expMap: map[string]string{"2.0.0": "2.0.0"},
+		},
+		{
+			name:   "exec dir",
+			args:   map[string]string{"exec": `["pwd"]`, "dir": tempDir},
+			expMap: map[string]string{tempDir: tempDir},
+		},
+		{
+			name: "lines",
+			args: map[string]string{"exec": `["printf", "1.0.0\n\n 1.1.0 \n2.0.0-rc.1\n"]`, "output": "lines"},
+			expMap: map[string]string{
+				"1.0.0":      "1.0.0",
+				"1.1.0":      "1.1.0",
+				"2.0.0-rc.1": "2.0.0-rc.1",
+			},
+		},
+		{
+			name: "kv",
+			args: map[string]string{"exec": `["printf", "# comment\nstable = 1.1.0\nbeta=2.0.0-rc.1\n"]`, "output": "kv"},
+			expMap: map[string]string{
+				"stable": "1.1.0",
+				"beta":   "2.0.0-rc.1",
+			},
+		},
+		{
+			name:   "kv invalid",
+			args:   map[string]string{"exec": `["printf", "1.1.0\n"]`, "output": "kv"},
+			expErr: true,
+		},
+		{
+			name:   "lines empty",
+			args:   map[string]string{"exec": `["true"]`, "output": "lines"},
+			expErr: true,
+		},
+		{
+			name: "json object",
+			args: map[string]string{"exec": `["echo", "{\"stable\": \"1.1.0\", \"minor\": 1.10, \"2.0.0\": {\"date\": \"2026-01-02\"}}"]`, "output": "json"},
+			expMap: map[string]string{
+				"stable": "1.1.0",
+				"minor":  "1.10",
+				"2.0.0":  "2.0.0",
+			},
+			expMeta: map[string]any{
+				"stable": "1.1.0",
+				"minor":  json.Number("1.10"),
+				"2.0.0":  map[string]any{"date": "2026-01-02"},
+			},
+		},
+		{
+			name: "json array",
+			args: map[string]string{"exec": `["echo", "[\"1.0.0\", {\"version\": \"1.1.0\", \"lts\": true}, {\"name\": \"other\"}, null]"]`, "output": "json"},
+			expMap: map[string]string{
+				"1.0.0": "1.0.0",
+				"1.1.0": "1.1.0",
+			},
+			expMeta: map[string]any{
+				"1.1.0": map[string]any{"version": "1.1.0", "lts": true},
+			},
+		},
+		{
+			name: "json array key",
+			args: map[string]string{"exec": `["echo", "[{\"tag\": \"v1.0.0\"}, {\"tag\": \"v1.1.0\"}]"]`, "output": "json", "jsonKey": "tag"},
+			expMap: map[string]string{
+				"v1.0.0": "v1.0.0",
+				"v1.1.0": "v1.1.0",
+			},
+			expMeta: map[string]any{
+				"v1.0.0": map[string]any{"tag": "v1.0.0"},
+				"v1.1.0": map[string]any{"tag": "v1.1.0"},
+			},
+		},
+		{
+			name:   "json scalar",
+			args:   map[string]string{"exec": `["echo", "\"1.0.0\""]`, "output": "json"},
+			expErr: true,
+		},
+		{
+			name:   "json invalid",
+			args:   map[string]string{"exec": `["echo", "{"]`, "output": "json"},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := newCustom(config.Source{
+				Name: tc.name,
+				Type: "custom",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != len(tc.expMap) {
+				t.Errorf("unexpected results, expected %v, received %v", tc.expMap, res.VerMap)
+			}
+			for k, v := range tc.expMap {
+				if res.VerMap[k] != v {
+					t.Errorf("unexpected value for %s, expected %s, received %s", k, v, res.VerMap[k])
+				}
+			}
+			if len(res.VerMeta) != len(tc.expMeta) {
+				t.Errorf("unexpected metadata, expected %v, received %v", tc.expMeta, res.VerMeta)
+			}
+			for k, v := range tc.expMeta {
+				exp, _ := json.Marshal(v)
+				received, _ := json.Marshal(res.VerMeta[k])
+				if string(exp) != string(received) {
+					t.Errorf("unexpected metadata for %s, expected %s, received %s", k, exp, received)
+				}
+			}
+		})
+	}
+}
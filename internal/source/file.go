@@ -0,0 +1,99 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+	"github.com/sudo-bmitch/version-bump/internal/datapath"
+)
+
+const (
+	fileArgFile   = "file"
+	fileArgType   = "type"
+	fileArgPath   = "path"
+	fileArgRegexp = "regexp"
+)
+
+// newFile reads the versions from a local file, using the same types as the url source.
+// The type defaults to regexp when only a regexp is provided, and otherwise to yaml or json based on the file extension.
+// Regexps are multi-line, so "^" and "$" match the start and end of each line.
+func newFile(conf config.Source) (Results, error) {
+	filename, ok := conf.Args[fileArgFile]
+	if !ok || filename == "" {
+		return Results{}, fmt.Errorf("file argument is required")
+	}
+	_, hasPath := conf.Args[fileArgPath]
+	typ := conf.Args[fileArgType]
+	if typ == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".yaml", ".yml":
+			typ = urlTypeYAML
+		case ".json":
+			typ = urlTypeJSON
+		}
+		if !hasPath && conf.Args[fileArgRegexp] != "" {
+			typ = urlTypeRegexp
+		} else if typ == "" {
+			typ = urlTypeJSON
+		}
+	}
+	var path *datapath.Path
+	var err error
+	switch typ {
+	case urlTypeJSON, urlTypeYAML:
+		if !hasPath {
+			return Results{}, fmt.Errorf("path argument is required for type %s", typ)
+		}
+		path, err = datapath.Parse(conf.Args[fileArgPath])
+		if err != nil {
+			return Results{}, err
+		}
+	case urlTypeRegexp:
+		if _, ok := conf.Args[fileArgRegexp]; !ok {
+			return Results{}, fmt.Errorf("regexp argument is required for type %s", typ)
+		}
+	default:
+		return Results{}, fmt.Errorf("unsupported type: %s", typ)
+	}
+	var re *regexp.Regexp
+	if expr, ok := conf.Args[fileArgRegexp]; ok {
+		re, err = regexp.Compile("(?m)" + expr)
+		if err != nil {
+			return Results{}, fmt.Errorf("failed to compile regexp %s: %w", expr, err)
+		}
+		if re.SubexpIndex(urlVersion) < 0 {
+			return Results{}, fmt.Errorf("regexp is missing a Version submatch (i.e. \"(?P<Version>\\d+)\"): %s", expr)
+		}
+	}
+	//#nosec G304 file to read is controlled by user running the command
+	body, err := os.ReadFile(filename)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	res, err := urlParse(body, typ, path, re)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to parse %s as %s: %w", filename, typ, err)
+	}
+	if len(res.VerMap) == 0 {
+		return Results{}, fmt.Errorf("no versions found in %s", filename)
+	}
+	return res, nil
+}
@@ -0,0 +1,148 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestFile(t *testing.T) {
+	tempDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":        "module example.com/test\n\ngo 1.26.1\n\ntoolchain go1.26.2\n",
+		"versions.yaml": "tools:\n  - name: golang\n    version: 1.26.1\n  - name: node\n    version: 24.1.0\n",
+		"numbers.yml":   "golang: 1.20\nnode: 24\n",
+		"versions.json": `{"golang": {"version": "go1.26.1", "sha256": "abc"}}`,
+		"versions.txt":  `{"golang": "1.26.1"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		args   map[string]string
+		expErr bool
+		expMap map[string]string
+	}{
+		{
+			name:   "missing file arg",
+			args:   map[string]string{"regexp": `^go (?P<Version>\S+)$`},
+			expErr: true,
+		},
+		{
+			name:   "missing file",
+			args:   map[string]string{"file": "missing.mod", "regexp": `^go (?P<Version>\S+)$`},
+			expErr: true,
+		},
+		{
+			name: "regexp",
+			args: map[string]string{"file": "go.mod", "regexp": `^go (?P<Version>\S+)$`},
+			expMap: map[string]string{
+				"1.26.1": "1.26.1",
+			},
+		},
+		{
+			name:   "regexp missing version",
+			args:   map[string]string{"file": "go.mod", "regexp": `^go (\S+)$`},
+			expErr: true,
+		},
+		{
+			name:   "regexp no match",
+			args:   map[string]string{"file": "go.mod", "regexp": `^godebug (?P<Version>\S+)$`},
+			expErr: true,
+		},
+		{
+			name: "yaml",
+			args: map[string]string{"file": "versions.yaml", "path": "$.tools[*].version"},
+			expMap: map[string]string{
+				"1.26.1": "1.26.1",
+				"24.1.0": "24.1.0",
+			},
+		},
+		{
+			name: "yaml numbers",
+			args: map[string]string{"file": "numbers.yml", "path": "$.*"},
+			expMap: map[string]string{
+				"1.20": "1.20",
+				"24":   "24",
+			},
+		},
+		{
+			name:   "yaml missing path",
+			args:   map[string]string{"file": "versions.yaml"},
+			expErr: true,
+		},
+		{
+			name: "json regexp",
+			args: map[string]string{"file": "versions.json", "path": "$.golang.version", "regexp": `^go(?P<Version>.*)$`},
+			expMap: map[string]string{
+				"1.26.1": "1.26.1",
+			},
+		},
+		{
+			name: "type override",
+			args: map[string]string{"file": "versions.txt", "type": "json", "path": "$.golang"},
+			expMap: map[string]string{
+				"1.26.1": "1.26.1",
+			},
+		},
+		{
+			name:   "invalid json",
+			args:   map[string]string{"file": "go.mod", "type": "json", "path": "$.go"},
+			expErr: true,
+		},
+		{
+			name:   "unsupported type",
+			args:   map[string]string{"file": "go.mod", "type": "toml", "path": "$.go"},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if file, ok := tc.args["file"]; ok {
+				tc.args["file"] = filepath.Join(tempDir, file)
+			}
+			res, err := newFile(config.Source{
+				Name: tc.name,
+				Type: "file",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != len(tc.expMap) {
+				t.Errorf("unexpected results, expected %v, received %v", tc.expMap, res.VerMap)
+			}
+			for k, v := range tc.expMap {
+				if res.VerMap[k] != v {
+					t.Errorf("unexpected value for %s, expected %s, received %s", k, v, res.VerMap[k])
+				}
+			}
+		})
+	}
+}
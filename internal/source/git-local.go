@@ -0,0 +1,372 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+const (
+	gitLocalArgPath       = "path"
+	gitLocalArgType       = "type"
+	gitLocalArgBranch     = "branch"
+	gitLocalArgRef        = "ref"
+	gitLocalArgMatch      = "match"
+	gitLocalArgTags       = "tags"
+	gitLocalArgDirty      = "dirty"
+	gitLocalTypeTag       = "tag"
+	gitLocalTypeBranch    = "branch"
+	gitLocalTypeDescribe  = "describe"
+	gitLocalDefaultPath   = "."
+	gitLocalDefaultRef    = "HEAD"
+	gitLocalAbbrev        = 7
+	gitLocalMaxCandidates = 10
+)
+
+func init() {
+	gob.Register(&GitLocalRef{})
+	gob.Register(&GitDescribe{})
+}
+
+// GitLocalRef is the metadata for a tag or branch in a local repository.
+type GitLocalRef struct {
+	Ref        string // Ref is the full name, e.g. refs/tags/v1.2.3
+	Name       string // Name is the short name, e.g. v1.2.3
+	Hash       string // Hash is the peeled commit hash
+	TagHash    string // TagHash is the hash of the tag object for annotated tags, and empty otherwise
+	CommitDate time.Time
+	Author     string
+	Tagger     string    // Tagger is empty unless the ref is an annotated tag
+	TagDate    time.Time // TagDate is the commit date unless the ref is an annotated tag
+}
+
+// Annotated is true for annotated tags.
+func (g *GitLocalRef) Annotated() bool {
+	return g.TagHash != ""
+}
+
+// GitDescribe is the metadata for the output of the describe type.
+type GitDescribe struct {
+	Tag      string
+	Distance int    // Distance is the number of commits after the tag
+	Hash     string // Hash is the full hash of the described commit
+	Dirty    bool
+}
+
+// newGitLocal reads the tags and branches from a repository on disk, without requiring a git binary.
+// The path defaults to the current directory, and parent directories are searched for the repository.
+func newGitLocal(conf config.Source) (Results, error) {
+	p := conf.Args[gitLocalArgPath]
+	if p == "" {
+		p = gitLocalDefaultPath
+	}
+	repo, err := git.PlainOpenWithOptions(p, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to open git repository %s: %w", p, err)
+	}
+	switch conf.Args[gitLocalArgType] {
+	case gitLocalTypeDescribe:
+		return gitLocalDescribe(conf, repo)
+	case gitLocalTypeTag, gitLocalTypeBranch, "":
+	default:
+		return Results{}, fmt.Errorf("unsupported type: %s", conf.Args[gitLocalArgType])
+	}
+	refs, err := gitLocalRefs(repo, conf.Args[gitLocalArgType] != gitLocalTypeBranch, conf.Args[gitLocalArgType] != gitLocalTypeTag)
+	if err != nil {
+		return Results{}, err
+	}
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	branch := conf.Args[gitLocalArgBranch]
+	for _, ref := range refs {
+		switch {
+		case conf.Args[gitLocalArgType] == gitLocalTypeTag:
+			res.VerMap[ref.Name] = ref.Name
+		case conf.Args[gitLocalArgType] == gitLocalTypeBranch && branch != "" && ref.Name != branch:
+			continue
+		default:
+			// a tag and branch with the same short name would otherwise overwrite each other
+			if prev, ok := res.VerMeta[ref.Name].(*GitLocalRef); ok {
+				return Results{}, fmt.Errorf("ref name %s is ambiguous, found %s and %s, set the type to tag or branch", ref.Name, prev.Ref, ref.Ref)
+			}
+			res.VerMap[ref.Name] = ref.Hash
+		}
+		res.VerMeta[ref.Name] = ref
+	}
+	if len(res.VerMap) == 0 && branch != "" {
+		return Results{}, fmt.Errorf("branch %s not found in %s", branch, p)
+	} else if len(res.VerMap) == 0 {
+		return Results{}, fmt.Errorf("no refs found in %s", p)
+	}
+	return res, nil
+}
+
+// gitLocalRefs returns the tags and branches from the repository.
+// Tags that do not point to a commit are skipped.
+func gitLocalRefs(repo *git.Repository, tags, branches bool) ([]*GitLocalRef, error) {
+	list := []*GitLocalRef{}
+	add := func(ref *plumbing.Reference) error {
+		m, err := gitLocalRefMeta(repo, ref)
+		if err != nil {
+			return err
+		}
+		if m != nil {
+			list = append(list, m)
+		}
+		return nil
+	}
+	if tags {
+		iter, err := repo.Tags()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags: %w", err)
+		}
+		if err := iter.ForEach(add); err != nil {
+			return nil, err
+		}
+	}
+	if branches {
+		iter, err := repo.Branches()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list branches: %w", err)
+		}
+		if err := iter.ForEach(add); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// gitLocalRefMeta returns the metadata for a ref, peeling annotated tags to the commit.
+// A nil value is returned for a tag that does not point to a commit.
+func gitLocalRefMeta(repo *git.Repository, ref *plumbing.Reference) (*GitLocalRef, error) {
+	m := &GitLocalRef{
+		Ref:  ref.Name().String(),
+		Name: ref.Name().Short(),
+		Hash: ref.Hash().String(),
+	}
+	hash := ref.Hash()
+	tag, err := repo.TagObject(hash)
+	switch {
+	case err == nil:
+		commit, err := tag.Commit()
+		if err != nil {
+			return nil, nil
+		}
+		m.TagHash = m.Hash
+		m.Hash = commit.Hash.String()
+		m.Tagger = fmt.Sprintf("%s <%s>", tag.Tagger.Name, tag.Tagger.Email)
+		m.TagDate = tag.Tagger.When
+		hash = commit.Hash
+	case !errors.Is(err, plumbing.ErrObjectNotFound):
+		return nil, fmt.Errorf("failed to read %s: %w", m.Ref, err)
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		if ref.Name().IsTag() {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read commit for %s: %w", m.Ref, err)
+	}
+	m.CommitDate = commit.Committer.When
+	m.Author = fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email)
+	if m.TagDate.IsZero() {
+		m.TagDate = m.CommitDate
+	}
+	return m, nil
+}
+
+// gitLocalDescribe returns the nearest tag to a commit, following the output of "git describe".
+// Only annotated tags are used unless the tags arg is true, and the match arg filters the tag names with a glob.
+func gitLocalDescribe(conf config.Source, repo *git.Repository) (Results, error) {
+	allTags := false
+	if val, ok := conf.Args[gitLocalArgTags]; ok {
+		var err error
+		allTags, err = strconv.ParseBool(val)
+		if err != nil {
+			return Results{}, fmt.Errorf("tags must be a bool value: \"%s\": %w", val, err)
+		}
+	}
+	rev := conf.Args[gitLocalArgRef]
+	if rev == "" {
+		rev = gitLocalDefaultRef
+	}
+	head, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to resolve %s: %w", rev, err)
+	}
+	refs, err := gitLocalRefs(repo, true, false)
+	if err != nil {
+		return Results{}, err
+	}
+	// prefer annotated tags, then the newest tag, when multiple tags point to the same commit
+	sort.SliceStable(refs, func(i, j int) bool {
+		if refs[i].Annotated() != refs[j].Annotated() {
+			return refs[i].Annotated()
+		}
+		if !refs[i].TagDate.Equal(refs[j].TagDate) {
+			return refs[i].TagDate.After(refs[j].TagDate)
+		}
+		return refs[i].Name < refs[j].Name
+	})
+	tagByCommit := map[plumbing.Hash]string{}
+	for _, ref := range refs {
+		if !allTags && !ref.Annotated() {
+			continue
+		}
+		if pattern := conf.Args[gitLocalArgMatch]; pattern != "" {
+			if ok, _ := path.Match(pattern, ref.Name); !ok {
+				continue
+			}
+		}
+		h := plumbing.NewHash(ref.Hash)
+		if _, ok := tagByCommit[h]; !ok {
+			tagByCommit[h] = ref.Name
+		}
+	}
+	// find the most recent tagged commits, and select the one with the fewest commits after the tag
+	// the history is read once, recording the parents to compute the distance to each candidate
+	candidates := []plumbing.Hash{}
+	parents := map[plumbing.Hash][]plumbing.Hash{}
+	err = gitLocalWalk(repo, *head, func(c *object.Commit) error {
+		parents[c.Hash] = c.ParentHashes
+		if _, ok := tagByCommit[c.Hash]; ok && len(candidates) < gitLocalMaxCandidates {
+			candidates = append(candidates, c.Hash)
+			if c.Hash == *head {
+				return storer.ErrStop
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Results{}, err
+	}
+	if len(candidates) == 0 {
+		return Results{}, fmt.Errorf("no tags found to describe %s", rev)
+	}
+	desc := &GitDescribe{Hash: head.String(), Distance: -1}
+	for i, dist := range gitLocalDistances(*head, parents, candidates) {
+		if desc.Distance < 0 || dist < desc.Distance {
+			desc.Tag = tagByCommit[candidates[i]]
+			desc.Distance = dist
+		}
+	}
+	out := desc.Tag
+	if desc.Distance > 0 {
+		out = fmt.Sprintf("%s-%d-g%s", desc.Tag, desc.Distance, desc.Hash[:gitLocalAbbrev])
+	}
+	if suffix := conf.Args[gitLocalArgDirty]; suffix != "" {
+		desc.Dirty, err = gitLocalDirty(repo)
+		if err != nil {
+			return Results{}, err
+		}
+		if desc.Dirty {
+			out += suffix
+		}
+	}
+	return Results{
+		VerMap:  map[string]string{out: out},
+		VerMeta: map[string]any{out: desc},
+	}, nil
+}
+
+// gitLocalWalk calls fn for each commit reachable from the hash, newest first.
+func gitLocalWalk(repo *git.Repository, hash plumbing.Hash, fn func(*object.Commit) error) error {
+	iter, err := repo.Log(&git.LogOptions{From: hash, Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return fmt.Errorf("failed to read history from %s: %w", hash.String(), err)
+	}
+	defer iter.Close()
+	err = iter.ForEach(fn)
+	if err != nil {
+		return fmt.Errorf("failed to read history from %s: %w", hash.String(), err)
+	}
+	return nil
+}
+
+// gitLocalDistances returns the number of commits reachable from head that are not reachable from each candidate.
+// The parents map contains every commit reachable from head, and there may be up to 64 candidates.
+// Each commit is visited after all of its children, collecting a bit for each candidate it is reachable from.
+func gitLocalDistances(head plumbing.Hash, parents map[plumbing.Hash][]plumbing.Hash, candidates []plumbing.Hash) []int {
+	children := map[plumbing.Hash]int{}
+	for _, list := range parents {
+		for _, p := range list {
+			children[p]++
+		}
+	}
+	reach := map[plumbing.Hash]uint64{}
+	for i, c := range candidates {
+		reach[c] |= 1 << i
+	}
+	dist := make([]int, len(candidates))
+	queue := []plumbing.Hash{head}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for i := range candidates {
+			if reach[cur]&(1<<i) == 0 {
+				dist[i]++
+			}
+		}
+		for _, p := range parents[cur] {
+			if _, ok := parents[p]; !ok {
+				continue
+			}
+			reach[p] |= reach[cur]
+			children[p]--
+			if children[p] == 0 {
+				queue = append(queue, p)
+			}
+		}
+	}
+	return dist
+}
+
+// gitLocalDirty reports whether tracked files in the worktree have changes, untracked files are ignored.
+func gitLocalDirty(repo *git.Repository) (bool, error) {
+	wt, err := repo.Worktree()
+	if errors.Is(err, git.ErrIsBareRepository) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to open worktree: %w", err)
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to get worktree status: %w", err)
+	}
+	for _, s := range status {
+		if s.Staging != git.Unmodified && s.Staging != git.Untracked {
+			return true, nil
+		}
+		if s.Worktree != git.Unmodified && s.Worktree != git.Untracked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,287 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestGitLocal(t *testing.T) {
+	work, _, hashes := gitTestRepo(t)
+	subDir := filepath.Join(work, "sub", "dir")
+	if err := os.MkdirAll(subDir, 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	describeRelease := "v1.1.0-1-g" + hashes["release"][:7]
+
+	tests := []struct {
+		name    string
+		args    map[string]string
+		setup   func(t *testing.T)
+		expErr  bool
+		expMap  map[string]string
+		expMeta func(t *testing.T, meta map[string]any)
+	}{
+		{
+			name: "commits",
+			args: map[string]string{},
+			expMap: map[string]string{
+				"v1.0.0":  hashes["v1.0.0"],
+				"v1.1.0":  hashes["v1.1.0"],
+				"main":    hashes["main"],
+				"release": hashes["release"],
+			},
+			expMeta: func(t *testing.T, meta map[string]any) {
+				ref, ok := meta["v1.1.0"].(*GitLocalRef)
+				if !ok {
+					t.Fatalf("missing metadata for v1.1.0: %v", meta)
+				}
+				if !ref.Annotated() || ref.TagHash != hashes["v1.1.0-tag"] || ref.Tagger != "Committer <committer@example.com>" {
+					t.Errorf("unexpected metadata for v1.1.0: %+v", ref)
+				}
+				if ref.CommitDate.UTC().Format("2006-01-02") != "2026-05-01" || ref.TagDate.UTC().Format("2006-01-02") != "2026-05-02" {
+					t.Errorf("unexpected dates for v1.1.0: %+v", ref)
+				}
+				ref, ok = meta["v1.0.0"].(*GitLocalRef)
+				if !ok {
+					t.Fatalf("missing metadata for v1.0.0: %v", meta)
+				}
+				if ref.Annotated() || ref.Author != "Author <author@example.com>" || !ref.TagDate.Equal(ref.CommitDate) {
+					t.Errorf("unexpected metadata for v1.0.0: %+v", ref)
+				}
+			},
+		},
+		{
+			name: "tags",
+			args: map[string]string{"type": "tag", "path": subDir},
+			expMap: map[string]string{
+				"v1.0.0": "v1.0.0",
+				"v1.1.0": "v1.1.0",
+			},
+		},
+		{
+			name: "branch",
+			args: map[string]string{"type": "branch", "branch": "release"},
+			expMap: map[string]string{
+				"release": hashes["release"],
+			},
+		},
+		{
+			name:   "branch missing",
+			args:   map[string]string{"type": "branch", "branch": "missing"},
+			expErr: true,
+		},
+		{
+			name:   "unknown type",
+			args:   map[string]string{"type": "unknown"},
+			expErr: true,
+		},
+		{
+			name: "describe",
+			args: map[string]string{"type": "describe"},
+			expMap: map[string]string{
+				describeRelease: describeRelease,
+			},
+			expMeta: func(t *testing.T, meta map[string]any) {
+				desc, ok := meta[describeRelease].(*GitDescribe)
+				if !ok {
+					t.Fatalf("missing metadata: %v", meta)
+				}
+				if desc.Tag != "v1.1.0" || desc.Distance != 1 || desc.Hash != hashes["release"] || desc.Dirty {
+					t.Errorf("unexpected metadata: %+v", desc)
+				}
+			},
+		},
+		{
+			name: "describe exact",
+			args: map[string]string{"type": "describe", "ref": "main"},
+			expMap: map[string]string{
+				"v1.1.0": "v1.1.0",
+			},
+		},
+		{
+			name: "describe lightweight match",
+			args: map[string]string{"type": "describe", "tags": "true", "match": "v1.0.*"},
+			expMap: map[string]string{
+				"v1.0.0-2-g" + hashes["release"][:7]: "v1.0.0-2-g" + hashes["release"][:7],
+			},
+		},
+		{
+			name:   "describe no annotated tag",
+			args:   map[string]string{"type": "describe", "ref": "v1.0.0"},
+			expErr: true,
+		},
+		{
+			name:   "describe invalid tags",
+			args:   map[string]string{"type": "describe", "tags": "maybe"},
+			expErr: true,
+		},
+		{
+			name: "describe untracked",
+			args: map[string]string{"type": "describe", "dirty": "-dirty"},
+			setup: func(t *testing.T) {
+				if err := os.WriteFile(filepath.Join(work, "untracked.txt"), []byte("new\n"), 0o644); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+			},
+			expMap: map[string]string{
+				describeRelease: describeRelease,
+			},
+		},
+		{
+			name: "describe dirty",
+			args: map[string]string{"type": "describe", "dirty": "-dirty"},
+			setup: func(t *testing.T) {
+				if err := os.WriteFile(filepath.Join(work, "version.txt"), []byte("1.2.0\n"), 0o644); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+			},
+			expMap: map[string]string{
+				describeRelease + "-dirty": describeRelease + "-dirty",
+			},
+		},
+		{
+			name: "ambiguous ref",
+			args: map[string]string{},
+			setup: func(t *testing.T) {
+				repo, err := git.PlainOpen(work)
+				if err != nil {
+					t.Fatalf("failed to open repo: %v", err)
+				}
+				err = repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("v1.0.0"), plumbing.NewHash(hashes["release"])))
+				if err != nil {
+					t.Fatalf("failed to create branch: %v", err)
+				}
+			},
+			expErr: true,
+		},
+		{
+			name: "ambiguous ref tags",
+			args: map[string]string{"type": "tag"},
+			expMap: map[string]string{
+				"v1.0.0": "v1.0.0",
+				"v1.1.0": "v1.1.0",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setup != nil {
+				tc.setup(t)
+			}
+			if _, ok := tc.args["path"]; !ok {
+				tc.args["path"] = work
+			}
+			res, err := newGitLocal(config.Source{
+				Name: tc.name,
+				Type: "git-local",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != len(tc.expMap) {
+				t.Errorf("unexpected results, expected %v, received %v", tc.expMap, res.VerMap)
+			}
+			for k, v := range tc.expMap {
+				if res.VerMap[k] != v {
+					t.Errorf("unexpected value for %s, expected %s, received %s", k, v, res.VerMap[k])
+				}
+			}
+			if tc.expMeta != nil {
+				tc.expMeta(t, res.VerMeta)
+			}
+		})
+	}
+}
+
+func TestGitLocalDistances(t *testing.T) {
+	// a is the root, b and c are on separate branches merged in d, and e is the head
+	h := map[string]plumbing.Hash{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		h[name] = plumbing.NewHash(strings.Repeat(name, 40))
+	}
+	parents := map[plumbing.Hash][]plumbing.Hash{
+		h["e"]: {h["d"]},
+		h["d"]: {h["b"], h["c"]},
+		h["c"]: {h["a"]},
+		h["b"]: {h["a"]},
+		h["a"]: {},
+	}
+	tests := []struct {
+		name       string
+		head       string
+		candidates []string
+		expect     []int
+	}{
+		{
+			name:       "head",
+			head:       "e",
+			candidates: []string{"e"},
+			expect:     []int{0},
+		},
+		{
+			name:       "merge",
+			head:       "e",
+			candidates: []string{"d", "c", "b", "a"},
+			expect:     []int{1, 3, 3, 4},
+		},
+		{
+			name:       "branch",
+			head:       "c",
+			candidates: []string{"a"},
+			expect:     []int{1},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cands := []plumbing.Hash{}
+			for _, c := range tc.candidates {
+				cands = append(cands, h[c])
+			}
+			// limit the parents to the commits reachable from head, matching the walk
+			reachable := map[plumbing.Hash][]plumbing.Hash{}
+			queue := []plumbing.Hash{h[tc.head]}
+			for len(queue) > 0 {
+				cur := queue[0]
+				queue = queue[1:]
+				if _, ok := reachable[cur]; ok {
+					continue
+				}
+				reachable[cur] = parents[cur]
+				queue = append(queue, parents[cur]...)
+			}
+			result := gitLocalDistances(h[tc.head], reachable, cands)
+			if !slices.Equal(result, tc.expect) {
+				t.Errorf("unexpected distances, expected %v, received %v", tc.expect, result)
+			}
+		})
+	}
+}
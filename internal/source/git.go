@@ -15,28 +15,41 @@
 package source
 
 import (
+	"encoding/gob"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	gitConfig "github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/storage/memory"
 
 	"github.com/sudo-bmitch/version-bump/internal/config"
 )
 
 const (
-	gitArgURL  = "url"
-	gitArgType = "type"
-	gitTypeTag = "tag"
+	gitArgURL     = "url"
+	gitArgType    = "type"
+	gitArgBranch  = "branch"
+	gitTypeTag    = "tag"
+	gitTypeBranch = "branch"
+	gitPeeled     = "^{}"
 )
 
 var gitState struct {
-	once         sync.Once
-	mu           sync.Mutex // mutex for cache access
-	cacheTags    map[string]*Results
-	cacheCommits map[string]*Results
+	once          sync.Once
+	mu            sync.Mutex // mutex for cache access
+	cacheTags     map[string]*Results
+	cacheCommits  map[string]*Results
+	cacheBranches map[string]*Results
+}
+
+func init() {
+	gob.Register(&GitRef{})
 }
 
 func newGit(conf config.Source) (Results, error) {
@@ -46,9 +59,13 @@ func newGit(conf config.Source) (Results, error) {
 	gitState.once.Do(func() {
 		gitState.cacheCommits = map[string]*Results{}
 		gitState.cacheTags = map[string]*Results{}
+		gitState.cacheBranches = map[string]*Results{}
 	})
-	if conf.Args[gitArgType] == gitTypeTag {
+	switch conf.Args[gitArgType] {
+	case gitTypeTag:
 		return gitTag(conf)
+	case gitTypeBranch:
+		return gitBranch(conf)
 	}
 	return gitCommit(conf)
 }
@@ -74,7 +91,8 @@ func gitCommit(conf config.Source) (Results, error) {
 		return Results{}, err
 	}
 	res := Results{
-		VerMap: map[string]string{},
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
 	}
 	// make a map of tags to hashes
 	for _, ref := range refs {
@@ -82,11 +100,14 @@ func gitCommit(conf config.Source) (Results, error) {
 	}
 	// loop over the map entries to prefer the peeled hash (underlying commit vs signed/annotated tag hash)
 	for k := range res.VerMap {
-		if _, ok := res.VerMap[k+"^{}"]; ok {
-			res.VerMap[k] = res.VerMap[k+"^{}"]
-			delete(res.VerMap, k+"^{}")
+		if _, ok := res.VerMap[k+gitPeeled]; ok {
+			res.VerMap[k] = res.VerMap[k+gitPeeled]
+			delete(res.VerMap, k+gitPeeled)
 		}
 	}
+	for name, meta := range gitRefMeta(conf.Args[gitArgURL], refs) {
+		res.VerMeta[name] = meta
+	}
 	if len(res.VerMap) == 0 {
 		return Results{}, fmt.Errorf("no tagged commits found on %s", conf.Args[gitArgURL])
 	}
@@ -105,15 +126,229 @@ func gitTag(conf config.Source) (Results, error) {
 		return Results{}, err
 	}
 	res := Results{
-		VerMap: map[string]string{},
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
 	}
 	// make a map of tags
 	for _, ref := range refs {
 		res.VerMap[ref.Name().Short()] = ref.Name().Short()
 	}
+	for name, meta := range gitRefMeta(conf.Args[gitArgURL], refs) {
+		res.VerMeta[name] = meta
+	}
 	if len(res.VerMap) == 0 {
 		return Results{}, fmt.Errorf("no tagged commits found on %s", conf.Args[gitArgURL])
 	}
 	gitState.cacheTags[conf.Args[gitArgURL]] = &res
 	return res, nil
 }
+
+// gitBranch returns the head commit of each branch, or of the branch in the branch arg.
+func gitBranch(conf config.Source) (Results, error) {
+	branch := conf.Args[gitArgBranch]
+	key := conf.Args[gitArgURL] + ":" + branch
+	gitState.mu.Lock()
+	defer gitState.mu.Unlock()
+	if r, ok := gitState.cacheBranches[key]; ok {
+		return *r, nil
+	}
+	refs, err := gitRefs(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	meta := gitRefMeta(conf.Args[gitArgURL], refs)
+	for _, ref := range refs {
+		name := ref.Name().Short()
+		if !ref.Name().IsBranch() || (branch != "" && name != branch) {
+			continue
+		}
+		res.VerMap[name] = ref.Hash().String()
+		res.VerMeta[name] = meta[name]
+	}
+	if len(res.VerMap) == 0 && branch != "" {
+		return Results{}, fmt.Errorf("branch %s not found on %s", branch, conf.Args[gitArgURL])
+	} else if len(res.VerMap) == 0 {
+		return Results{}, fmt.Errorf("no branches found on %s", conf.Args[gitArgURL])
+	}
+	gitState.cacheBranches[key] = &res
+	return res, nil
+}
+
+// gitRefMeta returns the metadata for each ref by the short name, combining annotated tags with the peeled commit.
+func gitRefMeta(url string, refs []*plumbing.Reference) map[string]*GitRef {
+	hashes := map[string]string{}
+	for _, ref := range refs {
+		if ref.Type() == plumbing.HashReference {
+			hashes[ref.Name().String()] = ref.Hash().String()
+		}
+	}
+	meta := map[string]*GitRef{}
+	for _, ref := range refs {
+		full := ref.Name().String()
+		if ref.Type() != plumbing.HashReference || strings.HasSuffix(full, gitPeeled) {
+			continue
+		}
+		m := &GitRef{
+			URL:  url,
+			Ref:  full,
+			Name: ref.Name().Short(),
+			Hash: hashes[full],
+		}
+		if peeled, ok := hashes[full+gitPeeled]; ok {
+			m.Hash = peeled
+			m.TagHash = hashes[full]
+		}
+		meta[m.Name] = m
+	}
+	return meta
+}
+
+// GitRef is the metadata for a ref on a remote repository.
+// The commit and tagger details are loaded with a shallow fetch of the ref when first requested.
+type GitRef struct {
+	URL     string
+	Ref     string // Ref is the full name, e.g. refs/tags/v1.2.3
+	Name    string // Name is the short name, e.g. v1.2.3
+	Hash    string // Hash is the peeled commit hash
+	TagHash string // TagHash is the hash of the tag object for annotated tags, and empty otherwise
+	mu      sync.Mutex
+	loaded  bool
+	err     error
+	details gitRefDetails
+}
+
+type gitRefDetails struct {
+	commitDate time.Time
+	author     string
+	tagger     string
+	tagDate    time.Time
+}
+
+// gitRefGob is the cached content of a [GitRef], including the details once loaded.
+type gitRefGob struct {
+	URL        string
+	Ref        string
+	Name       string
+	Hash       string
+	TagHash    string
+	Loaded     bool
+	CommitDate time.Time
+	Author     string
+	Tagger     string
+	TagDate    time.Time
+}
+
+// GobEncode includes the commit and tagger details in the cache when they were successfully loaded.
+func (g *GitRef) GobEncode() ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return gobEncode(gitRefGob{
+		URL:        g.URL,
+		Ref:        g.Ref,
+		Name:       g.Name,
+		Hash:       g.Hash,
+		TagHash:    g.TagHash,
+		Loaded:     g.loaded && g.err == nil,
+		CommitDate: g.details.commitDate,
+		Author:     g.details.author,
+		Tagger:     g.details.tagger,
+		TagDate:    g.details.tagDate,
+	})
+}
+
+// GobDecode restores the ref from the cache.
+func (g *GitRef) GobDecode(b []byte) error {
+	c := gitRefGob{}
+	if err := gobDecode(b, &c); err != nil {
+		return err
+	}
+	g.URL, g.Ref, g.Name, g.Hash, g.TagHash = c.URL, c.Ref, c.Name, c.Hash, c.TagHash
+	g.loaded = c.Loaded
+	g.details = gitRefDetails{
+		commitDate: c.CommitDate,
+		author:     c.Author,
+		tagger:     c.Tagger,
+		tagDate:    c.TagDate,
+	}
+	return nil
+}
+
+// cacheRestore is a no-op, the details are fetched using the URL.
+func (g *GitRef) cacheRestore(src config.Source) {}
+
+// Annotated is true for annotated tags.
+func (g *GitRef) Annotated() bool {
+	return g.TagHash != ""
+}
+
+// CommitDate returns the committer date of the commit.
+func (g *GitRef) CommitDate() (time.Time, error) {
+	err := g.load()
+	return g.details.commitDate, err
+}
+
+// Author returns the author of the commit as "name <email>".
+func (g *GitRef) Author() (string, error) {
+	err := g.load()
+	return g.details.author, err
+}
+
+// Tagger returns the tagger of an annotated tag as "name <email>", and is empty for other refs.
+func (g *GitRef) Tagger() (string, error) {
+	err := g.load()
+	return g.details.tagger, err
+}
+
+// TagDate returns the date of an annotated tag, and the commit date for other refs.
+func (g *GitRef) TagDate() (time.Time, error) {
+	err := g.load()
+	if g.details.tagDate.IsZero() {
+		return g.details.commitDate, err
+	}
+	return g.details.tagDate, err
+}
+
+// load fetches the ref with a depth of 1 to read the commit and tag objects.
+func (g *GitRef) load() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.loaded {
+		return g.err
+	}
+	g.loaded = true
+	st := memory.NewStorage()
+	rem := git.NewRemote(st, &gitConfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{g.URL},
+	})
+	err := rem.Fetch(&git.FetchOptions{
+		RefSpecs: []gitConfig.RefSpec{gitConfig.RefSpec("+" + g.Ref + ":" + g.Ref)},
+		Depth:    1,
+		Tags:     git.NoTags,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		g.err = fmt.Errorf("failed to fetch %s from %s: %w", g.Ref, g.URL, err)
+		return g.err
+	}
+	if g.TagHash != "" {
+		tag, err := object.GetTag(st, plumbing.NewHash(g.TagHash))
+		if err != nil {
+			g.err = fmt.Errorf("failed to read tag %s: %w", g.Name, err)
+			return g.err
+		}
+		g.details.tagger = fmt.Sprintf("%s <%s>", tag.Tagger.Name, tag.Tagger.Email)
+		g.details.tagDate = tag.Tagger.When
+	}
+	commit, err := object.GetCommit(st, plumbing.NewHash(g.Hash))
+	if err != nil {
+		g.err = fmt.Errorf("failed to read commit %s for %s: %w", g.Hash, g.Name, err)
+		return g.err
+	}
+	g.details.commitDate = commit.Committer.When
+	g.details.author = fmt.Sprintf("%s <%s>", commit.Author.Name, commit.Author.Email)
+	return nil
+}
@@ -0,0 +1,198 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+	"github.com/sudo-bmitch/version-bump/internal/template"
+)
+
+// gitTestRepo creates a bare repo with a lightweight tag v1.0.0, an annotated tag v1.1.0, and a release branch.
+// The work tree has release checked out with a tracked file, version.txt.
+// The returned values are the work tree path, the url of the bare clone, and a map of the commit hash for each ref.
+func gitTestRepo(t *testing.T) (string, string, map[string]string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command not available")
+	}
+	tempDir := t.TempDir()
+	work := filepath.Join(tempDir, "work")
+	bare := filepath.Join(tempDir, "bare.git")
+	env := append(os.Environ(),
+		"GIT_CONFIG_GLOBAL="+os.DevNull,
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=Author",
+		"GIT_AUTHOR_EMAIL=author@example.com",
+		"GIT_COMMITTER_NAME=Committer",
+		"GIT_COMMITTER_EMAIL=committer@example.com",
+	)
+	run := func(date string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = work
+		cmd.Env = append(env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	if err := os.MkdirAll(work, 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	hashes := map[string]string{}
+	run("", "init", "-q", "-b", "main")
+	run("2026-04-01T10:00:00Z", "commit", "-q", "--allow-empty", "-m", "first")
+	run("", "tag", "v1.0.0")
+	hashes["v1.0.0"] = run("", "rev-parse", "HEAD")
+	run("2026-05-01T10:00:00Z", "commit", "-q", "--allow-empty", "-m", "second")
+	run("2026-05-02T12:00:00Z", "tag", "-a", "v1.1.0", "-m", "release v1.1.0")
+	hashes["v1.1.0"] = run("", "rev-parse", "HEAD")
+	hashes["v1.1.0-tag"] = run("", "rev-parse", "v1.1.0")
+	run("", "checkout", "-q", "-b", "release")
+	if err := os.WriteFile(filepath.Join(work, "version.txt"), []byte("1.1.0\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	run("", "add", "version.txt")
+	run("2026-06-01T10:00:00Z", "commit", "-q", "-m", "third")
+	hashes["release"] = run("", "rev-parse", "HEAD")
+	hashes["main"] = hashes["v1.1.0"]
+	run("", "clone", "-q", "--bare", work, bare)
+	return work, "file://" + bare, hashes
+}
+
+func TestGitBranch(t *testing.T) {
+	_, url, hashes := gitTestRepo(t)
+
+	tests := []struct {
+		name   string
+		args   map[string]string
+		expErr bool
+		expMap map[string]string
+	}{
+		{
+			name: "named branch",
+			args: map[string]string{"type": "branch", "branch": "release"},
+			expMap: map[string]string{
+				"release": hashes["release"],
+			},
+		},
+		{
+			name: "all branches",
+			args: map[string]string{"type": "branch"},
+			expMap: map[string]string{
+				"main":    hashes["main"],
+				"release": hashes["release"],
+			},
+		},
+		{
+			name:   "missing branch",
+			args:   map[string]string{"type": "branch", "branch": "missing"},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.args["url"] = url
+			res, err := newGit(config.Source{
+				Name: tc.name,
+				Type: "git",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != len(tc.expMap) {
+				t.Errorf("unexpected results, expected %v, received %v", tc.expMap, res.VerMap)
+			}
+			for k, v := range tc.expMap {
+				if res.VerMap[k] != v {
+					t.Errorf("unexpected hash for %s, expected %s, received %s", k, v, res.VerMap[k])
+				}
+			}
+		})
+	}
+}
+
+func TestGitMeta(t *testing.T) {
+	_, url, hashes := gitTestRepo(t)
+	res, err := newGit(config.Source{
+		Name: "meta",
+		Type: "git",
+		Args: map[string]string{"url": url},
+	})
+	if err != nil {
+		t.Fatalf("failed: %v", err)
+	}
+	if res.VerMap["v1.1.0"] != hashes["v1.1.0"] {
+		t.Errorf("expected peeled hash for v1.1.0, expected %s, received %s", hashes["v1.1.0"], res.VerMap["v1.1.0"])
+	}
+
+	annotated, ok := res.VerMeta["v1.1.0"].(*GitRef)
+	if !ok {
+		t.Fatalf("missing metadata for v1.1.0: %v", res.VerMeta)
+	}
+	if !annotated.Annotated() || annotated.Hash != hashes["v1.1.0"] || annotated.TagHash != hashes["v1.1.0-tag"] || annotated.Ref != "refs/tags/v1.1.0" {
+		t.Errorf("unexpected metadata for v1.1.0: %+v", annotated)
+	}
+	tagger, err := annotated.Tagger()
+	if err != nil || tagger != "Committer <committer@example.com>" {
+		t.Errorf("unexpected tagger: %s, %v", tagger, err)
+	}
+	tagDate, err := annotated.TagDate()
+	if err != nil || !tagDate.Equal(time.Date(2026, 5, 2, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected tag date: %v, %v", tagDate, err)
+	}
+
+	light, ok := res.VerMeta["v1.0.0"].(*GitRef)
+	if !ok {
+		t.Fatalf("missing metadata for v1.0.0: %v", res.VerMeta)
+	}
+	if light.Annotated() || light.Hash != hashes["v1.0.0"] {
+		t.Errorf("unexpected metadata for v1.0.0: %+v", light)
+	}
+	author, err := light.Author()
+	if err != nil || author != "Author <author@example.com>" {
+		t.Errorf("unexpected author: %s, %v", author, err)
+	}
+	if tagger, err := light.Tagger(); err != nil || tagger != "" {
+		t.Errorf("unexpected tagger for lightweight tag: %s, %v", tagger, err)
+	}
+
+	// the commit date is loaded when the processor template requests it
+	out, err := template.String(`{{ .Version }} ({{ ((index .VerMeta .Version).CommitDate).Format "2006-01-02" }})`, struct {
+		Results
+		Version string
+	}{Results: res, Version: "v1.1.0"})
+	if err != nil {
+		t.Fatalf("failed to template: %v", err)
+	}
+	if out != "v1.1.0 (2026-05-01)" {
+		t.Errorf("unexpected template output: %s", out)
+	}
+}
@@ -15,13 +15,12 @@
 package source
 
 import (
-	"encoding/json"
+	"encoding/gob"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -29,11 +28,13 @@ import (
 )
 
 const (
+	ghrArgAPI             = "api"
 	ghrArgType            = "type"
 	ghrArgRepo            = "repo"
 	ghrArgArtifact        = "artifact"
 	ghrArgAllowDraft      = "allowDraft"
 	ghrArgAllowPrerelease = "allowPrerelease"
+	ghrDefaultAPI         = "https://api.github.com"
 )
 
 var ghrState struct {
@@ -45,6 +46,11 @@ var ghrState struct {
 	cacheNames     map[string]*Results
 }
 
+func init() {
+	gob.Register(&GHRelease{})
+	gob.Register(&GHAsset{})
+}
+
 func newGHRelease(conf config.Source) (Results, error) {
 	if _, ok := conf.Args[ghrArgRepo]; !ok {
 		return Results{}, fmt.Errorf("repo argument is required")
@@ -63,45 +69,62 @@ func newGHRelease(conf config.Source) (Results, error) {
 
 func ghrReleaseList(conf config.Source) ([]*GHRelease, error) {
 	repo := conf.Args[ghrArgRepo]
-	if releases, ok := ghrState.cacheReleases[repo]; ok {
-		return releases, nil
-	}
-	u, err := url.Parse("https://api.github.com/repos/" + repo + "/releases")
+	perPage, maxPages, err := httpPageArgs(conf)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse api url, check repo syntax (%s should be org/proj): %w", repo, err)
+		return nil, err
 	}
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	api := ghrAPI(conf)
+	key := fmt.Sprintf("%s:%s:%d:%d", api, repo, perPage, maxPages)
+	if releases, ok := ghrState.cacheReleases[key]; ok {
+		return releases, nil
 	}
-	req.Header.Add("Accept", "application/json")
-	token := os.Getenv("GH_TOKEN")
-	if token == "" {
-		token = os.Getenv("GITHUB_TOKEN")
+	u, err := ghrReleaseURL(api, repo, perPage)
+	if err != nil {
+		return nil, err
 	}
-	if token != "" {
-		req.SetBasicAuth("git", token)
+	// the GitHub token env vars are only used for the public GitHub API
+	defEnv := []string{}
+	if api == ghrDefaultAPI {
+		defEnv = []string{"GH_TOKEN", "GITHUB_TOKEN"}
 	}
-	//#nosec G704 config file containing URL fragments is controlled by user running the command
-	resp, err := ghrState.httpClient.Do(req)
+	auth, err := newHTTPAuth(conf, u, defEnv...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call releases API: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status from API, status: %d, body: %s", resp.StatusCode, string(b))
-	}
-	releases := []*GHRelease{}
-	err = json.NewDecoder(resp.Body).Decode(&releases)
+	releases, err := httpGetPages[*GHRelease](ghrState.httpClient, u.String(), auth, maxPages)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode release API response: %w", err)
+		return nil, fmt.Errorf("failed to list releases: %w", err)
 	}
 	// cache result for future requests
-	ghrState.cacheReleases[repo] = releases
+	ghrState.cacheReleases[key] = releases
 	return releases, nil
 }
 
+// ghrReleaseURL returns the url of the first page of releases.
+func ghrReleaseURL(api, repo string, perPage int) (*url.URL, error) {
+	u, err := url.Parse(api + "/repos/" + repo + "/releases")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse api url, check repo syntax (%s should be org/proj): %w", repo, err)
+	}
+	q := url.Values{
+		"per_page": []string{strconv.Itoa(perPage)},
+	}
+	// limit is used by Gitea and Forgejo, and is not sent to GitHub
+	if api != ghrDefaultAPI {
+		q.Set("limit", strconv.Itoa(perPage))
+	}
+	u.RawQuery = q.Encode()
+	return u, nil
+}
+
+// ghrAPI returns the base url of the API, without a trailing slash.
+func ghrAPI(conf config.Source) string {
+	if api := strings.TrimSuffix(conf.Args[ghrArgAPI], "/"); api != "" {
+		return api
+	}
+	return ghrDefaultAPI
+}
+
 func ghrReleaseName(conf config.Source) (Results, error) {
 	var err error
 	allowDraft := false
@@ -118,7 +141,11 @@ func ghrReleaseName(conf config.Source) (Results, error) {
 			return Results{}, fmt.Errorf("allowPrerelease must be a bool value: \"%s\": %w", val, err)
 		}
 	}
-	key := fmt.Sprintf("%s:%t:%t", conf.Args[ghrArgRepo], allowDraft, allowPrerelease)
+	perPage, maxPages, err := httpPageArgs(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	key := fmt.Sprintf("%s:%s:%d:%d:%t:%t", ghrAPI(conf), conf.Args[ghrArgRepo], perPage, maxPages, allowDraft, allowPrerelease)
 	ghrState.mu.Lock()
 	defer ghrState.mu.Unlock()
 	if r, ok := ghrState.cacheNames[key]; ok {
@@ -166,7 +193,11 @@ func ghrArtifact(conf config.Source) (Results, error) {
 	if !ok {
 		return Results{}, fmt.Errorf("missing arg \"artifact\"")
 	}
-	key := fmt.Sprintf("%s:%s:%t:%t", conf.Args[ghrArgRepo], artifactName, allowDraft, allowPrerelease)
+	perPage, maxPages, err := httpPageArgs(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	key := fmt.Sprintf("%s:%s:%d:%d:%s:%t:%t", ghrAPI(conf), conf.Args[ghrArgRepo], perPage, maxPages, artifactName, allowDraft, allowPrerelease)
 	ghrState.mu.Lock()
 	defer ghrState.mu.Unlock()
 	if r, ok := ghrState.cacheArtifacts[key]; ok {
@@ -247,3 +278,18 @@ func (t *GHTime) UnmarshalJSON(data []byte) (err error) {
 	}
 	return err
 }
+
+func (t GHTime) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + time.Time(t).Format(time.RFC3339) + `"`), nil
+}
+
+func (t GHTime) GobEncode() ([]byte, error) {
+	return time.Time(t).GobEncode()
+}
+
+func (t *GHTime) GobDecode(data []byte) error {
+	var tt time.Time
+	err := tt.GobDecode(data)
+	*t = GHTime(tt)
+	return err
+}
@@ -0,0 +1,263 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+// ghrTestServer returns a server with a paged list of releases for any repo in the test org.
+// The Authorization header of the last request to each repo is saved in auth.
+func ghrTestServer(t *testing.T, count int, auth map[string]string) *httptest.Server {
+	t.Helper()
+	releases := []*GHRelease{}
+	for i := count; i > 0; i-- {
+		releases = append(releases, &GHRelease{
+			TagName:    fmt.Sprintf("v%d", i),
+			Prerelease: i%4 == 0,
+			Assets: []*GHAsset{
+				{
+					Name:        "app",
+					DownloadURL: fmt.Sprintf("https://example.com/v%d/app", i),
+				},
+			},
+		})
+	}
+	mux := http.NewServeMux()
+	mu := sync.Mutex{}
+	mux.HandleFunc("GET /repos/test/{repo}/releases", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		auth[r.PathValue("repo")] = r.Header.Get("Authorization")
+		mu.Unlock()
+		perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+		if err != nil {
+			perPage = 30
+		}
+		page, err := strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil {
+			page = 1
+		}
+		start := min((page-1)*perPage, len(releases))
+		end := min(start+perPage, len(releases))
+		if end < len(releases) {
+			w.Header().Add("Link", fmt.Sprintf(`<http://%s%s?per_page=%d&page=%d>; rel="next", <http://%s%s?per_page=%d&page=%d>; rel="last"`,
+				r.Host, r.URL.Path, perPage, page+1, r.Host, r.URL.Path, perPage, (len(releases)+perPage-1)/perPage))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(releases[start:end])
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestGHRelease(t *testing.T) {
+	ts := ghrTestServer(t, 25, map[string]string{})
+
+	tests := []struct {
+		name     string
+		args     map[string]string
+		expErr   bool
+		expCount int
+		expVer   string
+		expVal   string
+	}{
+		{
+			name:     "default paging",
+			args:     map[string]string{},
+			expCount: 19,
+			expVer:   "v1",
+			expVal:   "v1",
+		},
+		{
+			name: "small pages",
+			args: map[string]string{
+				"perPage":         "7",
+				"allowPrerelease": "true",
+			},
+			expCount: 25,
+			expVer:   "v1",
+			expVal:   "v1",
+		},
+		{
+			name: "page cap",
+			args: map[string]string{
+				"perPage":         "5",
+				"maxPages":        "2",
+				"allowPrerelease": "true",
+			},
+			expCount: 10,
+			expVer:   "v16",
+			expVal:   "v16",
+		},
+		{
+			name: "unlimited pages",
+			args: map[string]string{
+				"perPage":  "1",
+				"maxPages": "0",
+				"type":     "artifact",
+				"artifact": "app",
+			},
+			expCount: 19,
+			expVer:   "v1",
+			expVal:   "https://example.com/v1/app",
+		},
+		{
+			name: "invalid page size",
+			args: map[string]string{
+				"perPage": "500",
+			},
+			expErr: true,
+		},
+		{
+			name: "invalid page cap",
+			args: map[string]string{
+				"maxPages": "-1",
+			},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.args["api"] = ts.URL + "/"
+			tc.args["repo"] = "test/paged"
+			res, err := newGHRelease(config.Source{
+				Name: tc.name,
+				Type: "gh-release",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != tc.expCount {
+				t.Errorf("unexpected number of results, expected %d, received %d", tc.expCount, len(res.VerMap))
+			}
+			if res.VerMap[tc.expVer] != tc.expVal {
+				t.Errorf("unexpected value for %s, expected %s, received %s", tc.expVer, tc.expVal, res.VerMap[tc.expVer])
+			}
+		})
+	}
+}
+
+func TestGHReleaseAuth(t *testing.T) {
+	auth := map[string]string{}
+	ts := ghrTestServer(t, 1, auth)
+	tempDir := t.TempDir()
+	tokenFile := filepath.Join(tempDir, "token")
+	err := os.WriteFile(tokenFile, []byte("file-token\n"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+	netrcFile := filepath.Join(tempDir, "netrc")
+	err = os.WriteFile(netrcFile, []byte("machine example.com login other password other\nmachine 127.0.0.1\n  login user\n  password pass\n"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write netrc file: %v", err)
+	}
+	t.Setenv("GH_TOKEN", "gh-token")
+	t.Setenv("TEST_GHR_TOKEN", "env-token")
+	t.Setenv("NETRC", netrcFile)
+
+	tests := []struct {
+		name    string
+		args    map[string]string
+		expAuth string
+	}{
+		{
+			name: "env",
+			args: map[string]string{
+				"tokenEnv":  "TEST_GHR_TOKEN",
+				"tokenFile": tokenFile,
+			},
+			expAuth: "Basic " + base64.StdEncoding.EncodeToString([]byte("git:env-token")),
+		},
+		{
+			name: "file",
+			args: map[string]string{
+				"tokenEnv":  "TEST_GHR_MISSING",
+				"tokenFile": tokenFile,
+			},
+			expAuth: "Basic " + base64.StdEncoding.EncodeToString([]byte("git:file-token")),
+		},
+		{
+			name:    "netrc",
+			args:    map[string]string{},
+			expAuth: "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass")),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.args["api"] = ts.URL
+			tc.args["repo"] = "test/auth-" + tc.name
+			_, err := newGHRelease(config.Source{
+				Name: tc.name,
+				Type: "gh-release",
+				Args: tc.args,
+			})
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if auth["auth-"+tc.name] != tc.expAuth {
+				t.Errorf("unexpected auth, expected %s, received %s", tc.expAuth, auth["auth-"+tc.name])
+			}
+		})
+	}
+}
+
+func TestGHReleaseURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		api    string
+		expect string
+	}{
+		{
+			name:   "github",
+			api:    ghrDefaultAPI,
+			expect: ghrDefaultAPI + "/repos/org/proj/releases?per_page=50",
+		},
+		{
+			name:   "forgejo",
+			api:    "https://forgejo.example.com/api/v1",
+			expect: "https://forgejo.example.com/api/v1/repos/org/proj/releases?limit=50&per_page=50",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := ghrReleaseURL(tc.api, "org/proj", 50)
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if u.String() != tc.expect {
+				t.Errorf("unexpected url, expected %s, received %s", tc.expect, u.String())
+			}
+		})
+	}
+}
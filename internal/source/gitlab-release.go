@@ -0,0 +1,334 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/gob"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+const (
+	glrArgAPI             = "api"
+	glrArgType            = "type"
+	glrArgProject         = "project"
+	glrArgArtifact        = "artifact"
+	glrArgAllowPrerelease = "allowPrerelease"
+	glrDefaultAPI         = "https://gitlab.com/api/v4"
+)
+
+var glrState struct {
+	once           sync.Once
+	httpClient     *http.Client
+	mu             sync.Mutex // mutex for cache access
+	cacheReleases  map[string][]*GLRelease
+	cacheArtifacts map[string]*Results
+	cacheNames     map[string]*Results
+	cacheTags      map[string]*Results
+}
+
+func init() {
+	gob.Register(&GLRelease{})
+	gob.Register(&GLLink{})
+	gob.Register(&GLTag{})
+}
+
+// newGLRelease lists the releases of a GitLab project, or the repository tags with the tag type.
+// The project may be the full path (group/proj) or the numeric ID.
+func newGLRelease(conf config.Source) (Results, error) {
+	if _, ok := conf.Args[glrArgProject]; !ok {
+		return Results{}, fmt.Errorf("project argument is required")
+	}
+	glrState.once.Do(func() {
+		glrState.httpClient = http.DefaultClient
+		glrState.cacheReleases = map[string][]*GLRelease{}
+		glrState.cacheArtifacts = map[string]*Results{}
+		glrState.cacheNames = map[string]*Results{}
+		glrState.cacheTags = map[string]*Results{}
+	})
+	switch conf.Args[glrArgType] {
+	case "artifact":
+		return glrArtifact(conf)
+	case "tag":
+		return glrTag(conf)
+	}
+	return glrReleaseName(conf)
+}
+
+func glrReleaseList(conf config.Source) ([]*GLRelease, error) {
+	perPage, maxPages, err := httpPageArgs(conf)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s:%s:%d:%d", glrAPI(conf), conf.Args[glrArgProject], perPage, maxPages)
+	if releases, ok := glrState.cacheReleases[key]; ok {
+		return releases, nil
+	}
+	releases, err := glrGetPages[*GLRelease](conf, "releases")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list releases: %w", err)
+	}
+	// cache result for future requests
+	glrState.cacheReleases[key] = releases
+	return releases, nil
+}
+
+// glrGetPages lists the entries of a project resource, e.g. releases or repository/tags.
+func glrGetPages[T any](conf config.Source, resource string) ([]T, error) {
+	project := conf.Args[glrArgProject]
+	perPage, maxPages, err := httpPageArgs(conf)
+	if err != nil {
+		return nil, err
+	}
+	api := glrAPI(conf)
+	// the project path is escaped into a single path component, e.g. group%2Fproj
+	u, err := url.Parse(api + "/projects/" + url.PathEscape(project) + "/" + resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse api url, check the api (%s) and project (%s): %w", api, project, err)
+	}
+	u.RawQuery = url.Values{
+		"per_page": []string{strconv.Itoa(perPage)},
+	}.Encode()
+	// the GitLab token env var is only used for the public GitLab API
+	defEnv := []string{}
+	if api == glrDefaultAPI {
+		defEnv = []string{"GITLAB_TOKEN"}
+	}
+	auth, err := newHTTPAuth(conf, u, defEnv...)
+	if err != nil {
+		return nil, err
+	}
+	// GitLab uses an access token rather than basic auth, so a netrc password is sent as the token
+	if auth.token == "" && auth.password != "" {
+		auth.token = auth.password
+	}
+	auth.header = "PRIVATE-TOKEN"
+	return httpGetPages[T](glrState.httpClient, u.String(), auth, maxPages)
+}
+
+// glrAPI returns the base url of the API, without a trailing slash.
+func glrAPI(conf config.Source) string {
+	if api := strings.TrimSuffix(conf.Args[glrArgAPI], "/"); api != "" {
+		return api
+	}
+	return glrDefaultAPI
+}
+
+// glrAllowPrerelease parses the allowPrerelease arg, defaulting to false.
+func glrAllowPrerelease(conf config.Source) (bool, error) {
+	val, ok := conf.Args[glrArgAllowPrerelease]
+	if !ok {
+		return false, nil
+	}
+	allow, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("allowPrerelease must be a bool value: \"%s\": %w", val, err)
+	}
+	return allow, nil
+}
+
+func glrReleaseName(conf config.Source) (Results, error) {
+	allowPrerelease, err := glrAllowPrerelease(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	perPage, maxPages, err := httpPageArgs(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	key := fmt.Sprintf("%s:%s:%d:%d:%t", glrAPI(conf), conf.Args[glrArgProject], perPage, maxPages, allowPrerelease)
+	glrState.mu.Lock()
+	defer glrState.mu.Unlock()
+	if r, ok := glrState.cacheNames[key]; ok {
+		return *r, nil
+	}
+	releases, err := glrReleaseList(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	for _, r := range releases {
+		if r.Prerelease() && !allowPrerelease {
+			continue
+		}
+		res.VerMap[r.TagName] = r.TagName
+		res.VerMeta[r.TagName] = r
+	}
+	if len(res.VerMap) <= 0 {
+		return Results{}, fmt.Errorf("no releases found")
+	}
+	glrState.cacheNames[key] = &res
+	return res, nil
+}
+
+func glrArtifact(conf config.Source) (Results, error) {
+	allowPrerelease, err := glrAllowPrerelease(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	artifactName, ok := conf.Args[glrArgArtifact]
+	if !ok {
+		return Results{}, fmt.Errorf("missing arg \"artifact\"")
+	}
+	perPage, maxPages, err := httpPageArgs(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	key := fmt.Sprintf("%s:%s:%d:%d:%s:%t", glrAPI(conf), conf.Args[glrArgProject], perPage, maxPages, artifactName, allowPrerelease)
+	glrState.mu.Lock()
+	defer glrState.mu.Unlock()
+	if r, ok := glrState.cacheArtifacts[key]; ok {
+		return *r, nil
+	}
+	releases, err := glrReleaseList(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	for _, r := range releases {
+		if r.Prerelease() && !allowPrerelease {
+			continue
+		}
+		for _, link := range r.Assets.Links {
+			if link.Name == artifactName {
+				res.VerMap[r.TagName] = link.DownloadURL()
+				res.VerMeta[r.TagName] = link
+				break
+			}
+		}
+	}
+	if len(res.VerMap) <= 0 {
+		return Results{}, fmt.Errorf("no releases found with artifact \"%s\"", artifactName)
+	}
+	glrState.cacheArtifacts[key] = &res
+	return res, nil
+}
+
+// glrTag lists the repository tags, which includes tags without a release.
+// Tags with a semver prerelease are skipped unless allowPrerelease is set.
+func glrTag(conf config.Source) (Results, error) {
+	allowPrerelease, err := glrAllowPrerelease(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	perPage, maxPages, err := httpPageArgs(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	key := fmt.Sprintf("%s:%s:%d:%d:%t", glrAPI(conf), conf.Args[glrArgProject], perPage, maxPages, allowPrerelease)
+	glrState.mu.Lock()
+	defer glrState.mu.Unlock()
+	if r, ok := glrState.cacheTags[key]; ok {
+		return *r, nil
+	}
+	tags, err := glrGetPages[*GLTag](conf, "repository/tags")
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to list tags: %w", err)
+	}
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	for _, t := range tags {
+		if t.Prerelease() && !allowPrerelease {
+			continue
+		}
+		res.VerMap[t.Name] = t.Name
+		res.VerMeta[t.Name] = t
+	}
+	if len(res.VerMap) <= 0 {
+		return Results{}, fmt.Errorf("no tags found")
+	}
+	glrState.cacheTags[key] = &res
+	return res, nil
+}
+
+type GLRelease struct {
+	TagName         string    `json:"tag_name"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description"`
+	CreatedAt       time.Time `json:"created_at"`
+	ReleasedAt      time.Time `json:"released_at"`
+	UpcomingRelease bool      `json:"upcoming_release"`
+	Commit          GLCommit  `json:"commit"`
+	Assets          GLAssets  `json:"assets"`
+}
+
+// Prerelease is true for upcoming releases and tags with a semver prerelease, since GitLab has no prerelease flag.
+func (r *GLRelease) Prerelease() bool {
+	if r.UpcomingRelease {
+		return true
+	}
+	v, err := semver.NewVersion(r.TagName)
+	return err == nil && v.Prerelease() != ""
+}
+
+type GLTag struct {
+	Name      string    `json:"name"`
+	Message   string    `json:"message"`
+	Target    string    `json:"target"`
+	Protected bool      `json:"protected"`
+	CreatedAt time.Time `json:"created_at"`
+	Commit    GLCommit  `json:"commit"`
+}
+
+// Prerelease is true for tags with a semver prerelease.
+func (t *GLTag) Prerelease() bool {
+	v, err := semver.NewVersion(t.Name)
+	return err == nil && v.Prerelease() != ""
+}
+
+type GLCommit struct {
+	ID            string    `json:"id"`
+	ShortID       string    `json:"short_id"`
+	Title         string    `json:"title"`
+	CreatedAt     time.Time `json:"created_at"`
+	CommittedDate time.Time `json:"committed_date"`
+}
+
+type GLAssets struct {
+	Links []*GLLink `json:"links"`
+}
+
+type GLLink struct {
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	DirectAssetURL string `json:"direct_asset_url"`
+	LinkType       string `json:"link_type"`
+}
+
+// DownloadURL returns the permanent direct asset url when available, and the link url otherwise.
+func (l *GLLink) DownloadURL() string {
+	if l.DirectAssetURL != "" {
+		return l.DirectAssetURL
+	}
+	return l.URL
+}
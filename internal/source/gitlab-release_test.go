@@ -0,0 +1,259 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestGLRelease(t *testing.T) {
+	releases := []*GLRelease{}
+	for i := 12; i > 0; i-- {
+		r := &GLRelease{
+			TagName: fmt.Sprintf("v1.%d.0", i),
+			Assets: GLAssets{
+				Links: []*GLLink{
+					{
+						Name: "app",
+						URL:  fmt.Sprintf("https://example.com/v1.%d.0/app", i),
+					},
+				},
+			},
+		}
+		if i%2 == 0 {
+			r.Assets.Links[0].DirectAssetURL = fmt.Sprintf("https://example.com/direct/v1.%d.0/app", i)
+		}
+		releases = append(releases, r)
+	}
+	releases = append([]*GLRelease{
+		{TagName: "v2.0.0-rc.1"},
+		{TagName: "v1.13.0", UpcomingRelease: true},
+	}, releases...)
+	tags := []*GLTag{
+		{Name: "v2.0.0-rc.1"},
+	}
+	for i := 14; i > 0; i-- {
+		tags = append(tags, &GLTag{
+			Name:   fmt.Sprintf("v1.%d.0", i),
+			Commit: GLCommit{ID: fmt.Sprintf("%040d", i)},
+		})
+	}
+	mux := http.NewServeMux()
+	// the project path is escaped, the raw path is checked to verify the escaping
+	mux.HandleFunc("GET /api/v4/projects/{project}/releases", func(w http.ResponseWriter, r *http.Request) {
+		glrTestPages(w, r, "releases", releases)
+	})
+	mux.HandleFunc("GET /api/v4/projects/{project}/repository/tags", func(w http.ResponseWriter, r *http.Request) {
+		glrTestPages(w, r, "repository/tags", tags)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	t.Setenv("TEST_GLR_TOKEN", "secret")
+
+	tests := []struct {
+		name     string
+		args     map[string]string
+		expErr   bool
+		expCount int
+		expVer   string
+		expVal   string
+	}{
+		{
+			name:     "names",
+			args:     map[string]string{},
+			expCount: 12,
+			expVer:   "v1.1.0",
+			expVal:   "v1.1.0",
+		},
+		{
+			name: "project id",
+			args: map[string]string{
+				"project":         "42",
+				"allowPrerelease": "true",
+			},
+			expCount: 14,
+			expVer:   "v2.0.0-rc.1",
+			expVal:   "v2.0.0-rc.1",
+		},
+		{
+			name: "pages",
+			args: map[string]string{
+				"perPage":  "5",
+				"maxPages": "2",
+			},
+			expCount: 8,
+			expVer:   "v1.5.0",
+			expVal:   "v1.5.0",
+		},
+		{
+			name: "prerelease only",
+			args: map[string]string{
+				"perPage":  "1",
+				"maxPages": "1",
+			},
+			expErr: true,
+		},
+		{
+			name: "artifact",
+			args: map[string]string{
+				"type":     "artifact",
+				"artifact": "app",
+				"perPage":  "3",
+			},
+			expCount: 12,
+			expVer:   "v1.1.0",
+			expVal:   "https://example.com/v1.1.0/app",
+		},
+		{
+			name: "artifact direct",
+			args: map[string]string{
+				"type":     "artifact",
+				"artifact": "app",
+			},
+			expCount: 12,
+			expVer:   "v1.2.0",
+			expVal:   "https://example.com/direct/v1.2.0/app",
+		},
+		{
+			name: "artifact missing",
+			args: map[string]string{
+				"type":     "artifact",
+				"artifact": "missing",
+			},
+			expErr: true,
+		},
+		{
+			name: "tags",
+			args: map[string]string{
+				"type":    "tag",
+				"perPage": "4",
+			},
+			expCount: 14,
+			expVer:   "v1.14.0",
+			expVal:   "v1.14.0",
+		},
+		{
+			name: "tags prerelease",
+			args: map[string]string{
+				"type":            "tag",
+				"project":         "42",
+				"allowPrerelease": "true",
+			},
+			expCount: 15,
+			expVer:   "v2.0.0-rc.1",
+			expVal:   "v2.0.0-rc.1",
+		},
+		{
+			name: "tags pages",
+			args: map[string]string{
+				"type":     "tag",
+				"perPage":  "5",
+				"maxPages": "2",
+			},
+			expCount: 9,
+			expVer:   "v1.6.0",
+			expVal:   "v1.6.0",
+		},
+		{
+			name: "tags unauthorized",
+			args: map[string]string{
+				"type":     "tag",
+				"tokenEnv": "TEST_GLR_MISSING",
+				"perPage":  "7",
+			},
+			expErr: true,
+		},
+		{
+			name: "unauthorized",
+			args: map[string]string{
+				"tokenEnv": "TEST_GLR_MISSING",
+				"perPage":  "7", // avoid the cached list from other tests
+			},
+			expErr: true,
+		},
+		{
+			name: "invalid prerelease",
+			args: map[string]string{
+				"allowPrerelease": "maybe",
+			},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.args["api"] = ts.URL + "/api/v4/"
+			if _, ok := tc.args["project"]; !ok {
+				tc.args["project"] = "group/proj"
+			}
+			if _, ok := tc.args["tokenEnv"]; !ok {
+				tc.args["tokenEnv"] = "TEST_GLR_TOKEN"
+			}
+			res, err := newGLRelease(config.Source{
+				Name: tc.name,
+				Type: "gitlab-release",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != tc.expCount {
+				t.Errorf("unexpected number of results, expected %d, received %d", tc.expCount, len(res.VerMap))
+			}
+			if res.VerMap[tc.expVer] != tc.expVal {
+				t.Errorf("unexpected value for %s, expected %s, received %s", tc.expVer, tc.expVal, res.VerMap[tc.expVer])
+			}
+		})
+	}
+}
+
+// glrTestPages returns a page of entries for a project resource, with a Link header to the next page.
+func glrTestPages[T any](w http.ResponseWriter, r *http.Request, resource string, list []T) {
+	if r.URL.EscapedPath() != "/api/v4/projects/group%2Fproj/"+resource && r.PathValue("project") != "42" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+	if err != nil {
+		perPage = 20
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		page = 1
+	}
+	start := min((page-1)*perPage, len(list))
+	end := min(start+perPage, len(list))
+	if end < len(list) {
+		w.Header().Add("Link", fmt.Sprintf(`<http://%s%s?per_page=%d&page=%d>; rel="next"`, r.Host, r.URL.EscapedPath(), perPage, page+1))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(list[start:end])
+}
@@ -0,0 +1,447 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+const (
+	gomodArgModule     = "module"
+	gomodArgProxy      = "proxy"
+	gomodArgType       = "type"
+	gomodTypeList      = "list"
+	gomodTypeLatest    = "latest"
+	gomodDefaultProxy  = "https://proxy.golang.org,direct"
+	gomodPublicProxy   = "https://proxy.golang.org"
+	gomodProxyDirect   = "direct"
+	gomodProxyOff      = "off"
+	gomodEnvProxy      = "GOPROXY"
+	gomodEnvNoProxy    = "GONOPROXY"
+	gomodEnvPrivate    = "GOPRIVATE"
+	gomodIncompatible  = "+incompatible"
+	gomodGopkgInPrefix = "gopkg.in/"
+)
+
+// errGomodNotFound is returned when a proxy does not have the module, allowing the next proxy to be tried.
+var errGomodNotFound = errors.New("module not found")
+
+var gomodState struct {
+	once       sync.Once
+	httpClient *http.Client
+	mu         sync.Mutex // mutex for cache access
+	cache      map[string]*Results
+}
+
+func init() {
+	gob.Register(&GoModInfo{})
+}
+
+// GoModInfo is the version info returned by a module proxy.
+// The time is requested from the proxy when first used.
+type GoModInfo struct {
+	Version string
+	URL     string // URL is the .info url for the version
+	mu      sync.Mutex
+	loaded  bool
+	err     error
+	time    time.Time
+	conf    config.Source // conf is used for the credentials
+	auth    *httpAuth
+}
+
+// gomodInfoGob is the cached content of a [GoModInfo], including the time once loaded.
+type gomodInfoGob struct {
+	Version string
+	URL     string
+	Loaded  bool
+	Time    time.Time
+}
+
+// gomodInfoResp is the json response for a version info request.
+type gomodInfoResp struct {
+	Version string    `json:"Version"`
+	Time    time.Time `json:"Time"`
+}
+
+// Time returns the commit time of the version.
+func (i *GoModInfo) Time() (time.Time, error) {
+	err := i.load()
+	return i.time, err
+}
+
+// GobEncode includes the time in the cache when it was successfully loaded.
+func (i *GoModInfo) GobEncode() ([]byte, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return gobEncode(gomodInfoGob{
+		Version: i.Version,
+		URL:     i.URL,
+		Loaded:  i.loaded && i.err == nil,
+		Time:    i.time,
+	})
+}
+
+// GobDecode restores the info from the cache.
+func (i *GoModInfo) GobDecode(b []byte) error {
+	c := gomodInfoGob{}
+	if err := gobDecode(b, &c); err != nil {
+		return err
+	}
+	i.Version = c.Version
+	i.URL = c.URL
+	i.loaded = c.Loaded
+	i.time = c.Time
+	return nil
+}
+
+// cacheRestore sets the source config to look up the credentials, since they are not included in the cache.
+func (i *GoModInfo) cacheRestore(src config.Source) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.conf = src
+}
+
+// load requests the version info from the proxy.
+func (i *GoModInfo) load() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.loaded {
+		return i.err
+	}
+	i.loaded = true
+	gomodSetup()
+	if i.auth == nil {
+		u, err := url.Parse(i.URL)
+		if err != nil {
+			i.err = fmt.Errorf("failed to parse %s: %w", i.URL, err)
+			return i.err
+		}
+		i.auth, err = newHTTPAuth(i.conf, u)
+		if err != nil {
+			i.err = err
+			return i.err
+		}
+		i.auth.bearer = true
+	}
+	info, err := gomodInfo(i.URL, i.auth)
+	if err != nil {
+		i.err = err
+		return i.err
+	}
+	i.time = info.Time
+	return nil
+}
+
+// gomodProxy is an entry from GOPROXY.
+type gomodProxy struct {
+	url    string
+	anyErr bool // anyErr is true when any error tries the next proxy (separated by "|")
+}
+
+// newGoMod lists the versions of a Go module from a GOPROXY compatible server.
+// The proxy arg overrides the GOPROXY, GONOPROXY, and GOPRIVATE settings from the environment.
+// Direct lookups are not supported, so modules matching GONOPROXY or GOPRIVATE are queried
+// from the GOPROXY entries other than "direct", "off", and the public proxy.golang.org.
+// When no other proxy is configured, those modules return an error.
+func newGoMod(conf config.Source) (Results, error) {
+	module, ok := conf.Args[gomodArgModule]
+	if !ok || module == "" {
+		return Results{}, fmt.Errorf("module argument is required")
+	}
+	typ := conf.Args[gomodArgType]
+	if typ == "" {
+		typ = gomodTypeList
+	}
+	if typ != gomodTypeList && typ != gomodTypeLatest {
+		return Results{}, fmt.Errorf("unsupported type: %s", typ)
+	}
+	gomodSetup()
+	proxies, err := gomodProxies(conf, module)
+	if err != nil {
+		return Results{}, err
+	}
+	key := fmt.Sprintf("%s:%v:%s", module, proxies, typ)
+	gomodState.mu.Lock()
+	defer gomodState.mu.Unlock()
+	if r, ok := gomodState.cache[key]; ok {
+		return *r, nil
+	}
+	errs := []error{}
+	for _, proxy := range proxies {
+		var res Results
+		switch proxy.url {
+		case gomodProxyDirect:
+			err = fmt.Errorf("direct module lookups are not supported, use the git source for %s", module)
+		case gomodProxyOff:
+			err = fmt.Errorf("module lookups are disabled by GOPROXY=off")
+		default:
+			res, err = gomodQuery(conf, proxy.url, module, typ)
+		}
+		if err == nil {
+			gomodState.cache[key] = &res
+			return res, nil
+		}
+		errs = append(errs, err)
+		if !proxy.anyErr && !errors.Is(err, errGomodNotFound) {
+			break
+		}
+	}
+	return Results{}, fmt.Errorf("failed to query module %s: %w", module, errors.Join(errs...))
+}
+
+// gomodSetup initializes the shared state, including for metadata loaded from the cache.
+func gomodSetup() {
+	gomodState.once.Do(func() {
+		gomodState.httpClient = http.DefaultClient
+		gomodState.cache = map[string]*Results{}
+	})
+}
+
+// gomodProxies returns the list of proxies to query for a module.
+func gomodProxies(conf config.Source, module string) ([]gomodProxy, error) {
+	private := false
+	list, ok := conf.Args[gomodArgProxy]
+	if !ok {
+		noProxy := os.Getenv(gomodEnvNoProxy)
+		if noProxy == "" {
+			noProxy = os.Getenv(gomodEnvPrivate)
+		}
+		private = gomodMatchPrefix(noProxy, module)
+		list = os.Getenv(gomodEnvProxy)
+	}
+	if list == "" {
+		list = gomodDefaultProxy
+	}
+	proxies := []gomodProxy{}
+	for list != "" {
+		entry := list
+		sep := byte(0)
+		if i := strings.IndexAny(list, ",|"); i >= 0 {
+			entry, sep, list = list[:i], list[i], list[i+1:]
+		} else {
+			list = ""
+		}
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if entry != gomodProxyDirect && entry != gomodProxyOff {
+			u, err := url.Parse(entry)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return nil, fmt.Errorf("unsupported GOPROXY entry: %s", entry)
+			}
+			entry = strings.TrimSuffix(entry, "/")
+		}
+		// private modules skip the entries that would look up the module directly or publicly
+		if private && (entry == gomodProxyDirect || entry == gomodProxyOff || entry == gomodPublicProxy) {
+			continue
+		}
+		proxies = append(proxies, gomodProxy{url: entry, anyErr: sep == '|'})
+	}
+	if private && len(proxies) == 0 {
+		return nil, fmt.Errorf("module %s matches %s or %s and %s has no private proxy, set the proxy arg or use the git source", module, gomodEnvNoProxy, gomodEnvPrivate, gomodEnvProxy)
+	}
+	if len(proxies) == 0 {
+		return nil, fmt.Errorf("GOPROXY list is empty")
+	}
+	return proxies, nil
+}
+
+// gomodMatchPrefix reports whether the module matches any of the comma separated glob patterns.
+// Each pattern matches the same number of leading path elements, following the GOPRIVATE syntax.
+func gomodMatchPrefix(patterns, module string) bool {
+	for _, pattern := range strings.Split(patterns, ",") {
+		pattern = strings.TrimSuffix(strings.TrimSpace(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+		n := strings.Count(pattern, "/") + 1
+		prefix := module
+		for i := 0; i < len(prefix); i++ {
+			if prefix[i] == '/' {
+				n--
+				if n == 0 {
+					prefix = prefix[:i]
+					break
+				}
+			}
+		}
+		if n > 1 {
+			continue
+		}
+		if ok, _ := path.Match(pattern, prefix); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// gomodEscape encodes a module path or version for the proxy, replacing upper case letters with "!" and the lower case letter.
+func gomodEscape(s string) string {
+	sb := strings.Builder{}
+	for _, r := range s {
+		if 'A' <= r && r <= 'Z' {
+			sb.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// gomodQuery requests the versions of a module from a single proxy.
+func gomodQuery(conf config.Source, proxy, module, typ string) (Results, error) {
+	base := proxy + "/" + gomodEscape(module)
+	u, err := url.Parse(base)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to parse proxy url %s: %w", base, err)
+	}
+	auth, err := newHTTPAuth(conf, u)
+	if err != nil {
+		return Results{}, err
+	}
+	auth.bearer = true
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	versions := []string{}
+	if typ == gomodTypeList {
+		b, err := gomodGet(base+"/@v/list", auth)
+		if err != nil {
+			return Results{}, err
+		}
+		for _, ver := range strings.Fields(string(b)) {
+			if gomodMajorMatch(module, ver) {
+				versions = append(versions, ver)
+			}
+		}
+	}
+	// a module without any tagged versions returns the latest pseudo-version
+	if len(versions) == 0 {
+		info, err := gomodInfo(base+"/@latest", auth)
+		if err != nil {
+			return Results{}, err
+		}
+		res.VerMap[info.Version] = info.Version
+		res.VerMeta[info.Version] = &GoModInfo{
+			Version: info.Version,
+			URL:     base + "/@v/" + gomodEscape(info.Version) + ".info",
+			loaded:  true,
+			time:    info.Time,
+			conf:    conf,
+			auth:    auth,
+		}
+		return res, nil
+	}
+	// the info for each version is only requested when the time is used
+	for _, ver := range versions {
+		res.VerMap[ver] = ver
+		res.VerMeta[ver] = &GoModInfo{
+			Version: ver,
+			URL:     base + "/@v/" + gomodEscape(ver) + ".info",
+			conf:    conf,
+			auth:    auth,
+		}
+	}
+	return res, nil
+}
+
+// gomodMajorMatch reports whether the major version is valid for the module path.
+// Paths ending with /vN (or .vN for gopkg.in) only include that major version,
+// other paths include v0, v1, and +incompatible versions.
+func gomodMajorMatch(module, ver string) bool {
+	v, err := semver.NewVersion(ver)
+	if err != nil {
+		return false
+	}
+	pathMajor := ""
+	if strings.HasPrefix(module, gomodGopkgInPrefix) {
+		if i := strings.LastIndex(module, ".v"); i >= 0 {
+			pathMajor = module[i+2:]
+		}
+		// gopkg.in/pkg.v1 includes v0 versions
+		if pathMajor == "1" && v.Major() == 0 {
+			return true
+		}
+	} else if i := strings.LastIndex(module, "/v"); i >= 0 {
+		if n, err := strconv.Atoi(module[i+2:]); err == nil && n >= 2 {
+			pathMajor = module[i+2:]
+		}
+	}
+	if pathMajor == "" {
+		return v.Major() <= 1 || strings.HasSuffix(ver, gomodIncompatible)
+	}
+	return strconv.FormatUint(v.Major(), 10) == pathMajor && !strings.HasSuffix(ver, gomodIncompatible)
+}
+
+// gomodInfo requests the info for a single version.
+func gomodInfo(u string, auth *httpAuth) (*gomodInfoResp, error) {
+	b, err := gomodGet(u, auth)
+	if err != nil {
+		return nil, err
+	}
+	info := gomodInfoResp{}
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", u, err)
+	}
+	if info.Version == "" {
+		return nil, fmt.Errorf("version missing from %s", u)
+	}
+	return &info, nil
+}
+
+// gomodGet returns the body of a proxy request, or errGomodNotFound for a 404 or 410 status.
+func gomodGet(u string, auth *httpAuth) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	auth.set(req)
+	//#nosec G704 proxy URL is controlled by user running the command
+	resp, err := gomodState.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", u, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return b, nil
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%w: %s: %s", errGomodNotFound, u, strings.TrimSpace(string(b)))
+	}
+	return nil, fmt.Errorf("unexpected status from %s, status: %d, body: %s", u, resp.StatusCode, string(b))
+}
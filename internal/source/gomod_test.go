@@ -0,0 +1,424 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestGoMod(t *testing.T) {
+	// modules are stored by their escaped path
+	modules := map[string][]string{
+		"github.com/!azure/tool":             {"v0.9.0", "v1.0.0", "v1.1.0", "v2.0.0+incompatible"},
+		"github.com/!azure/tool/v3":          {"v3.0.0", "v3.1.0", "v1.2.0"},
+		"gopkg.in/yaml.v3":                   {"v3.0.0", "v3.0.1"},
+		"example.com/untagged":               {},
+		"example.com/badinfo":                {"v1.0.0", "v1.1.0"},
+		"git.internal.example.com/team/tool": {"v1.0.0"},
+	}
+	infoCount := atomic.Int32{}
+	mux := http.NewServeMux()
+	proxy := func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(r.URL.Path, "/proxy/")
+		if strings.HasSuffix(p, "/@v/list") {
+			vers, ok := modules[strings.TrimSuffix(p, "/@v/list")]
+			if !ok {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(strings.Join(vers, "\n")))
+			return
+		}
+		if mod, ok := strings.CutSuffix(p, "/@latest"); ok {
+			if _, ok := modules[mod]; !ok {
+				http.Error(w, "not found", http.StatusGone)
+				return
+			}
+			_, _ = w.Write([]byte(`{"Version":"v0.0.0-20240102030405-abcdefabcdef","Time":"2024-01-02T03:04:05Z"}`))
+			return
+		}
+		_, ver, ok := strings.Cut(p, "/@v/")
+		if !ok || !strings.HasSuffix(ver, ".info") {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		ver = strings.TrimSuffix(ver, ".info")
+		infoCount.Add(1)
+		if strings.HasPrefix(p, "example.com/badinfo/") && ver == "v1.1.0" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"Version":"%s","Time":"2024-05-01T00:00:00Z","Origin":{"VCS":"git"}}`, ver)
+	}
+	mux.HandleFunc("GET /proxy/", proxy)
+	// the auth proxy requires a bearer token
+	mux.HandleFunc("GET /auth/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.URL.Path = "/proxy/" + strings.TrimPrefix(r.URL.Path, "/auth/")
+		proxy(w, r)
+	})
+	mux.HandleFunc("GET /broken/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("GET /empty/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	t.Setenv("GOPROXY", ts.URL+"/proxy")
+	t.Setenv("GONOPROXY", "")
+	t.Setenv("GOPRIVATE", "*.internal.example.com,example.org/private")
+
+	tests := []struct {
+		name    string
+		args    map[string]string
+		expErr  bool
+		expVers []string
+	}{
+		{
+			name:    "env proxy",
+			args:    map[string]string{"module": "github.com/Azure/tool"},
+			expVers: []string{"v0.9.0", "v1.0.0", "v1.1.0", "v2.0.0+incompatible"},
+		},
+		{
+			name:    "major suffix",
+			args:    map[string]string{"module": "github.com/Azure/tool/v3"},
+			expVers: []string{"v3.0.0", "v3.1.0"},
+		},
+		{
+			name:    "gopkg.in",
+			args:    map[string]string{"module": "gopkg.in/yaml.v3"},
+			expVers: []string{"v3.0.0", "v3.0.1"},
+		},
+		{
+			name:    "pseudo-version",
+			args:    map[string]string{"module": "example.com/untagged"},
+			expVers: []string{"v0.0.0-20240102030405-abcdefabcdef"},
+		},
+		{
+			name: "latest",
+			args: map[string]string{
+				"module": "github.com/Azure/tool",
+				"type":   "latest",
+			},
+			expVers: []string{"v0.0.0-20240102030405-abcdefabcdef"},
+		},
+		{
+			name: "not found falls through",
+			args: map[string]string{
+				"module": "github.com/Azure/tool",
+				"proxy":  ts.URL + "/empty," + ts.URL + "/proxy/",
+			},
+			expVers: []string{"v0.9.0", "v1.0.0", "v1.1.0", "v2.0.0+incompatible"},
+		},
+		{
+			name: "error stops on comma",
+			args: map[string]string{
+				"module": "github.com/Azure/tool",
+				"proxy":  ts.URL + "/broken," + ts.URL + "/proxy",
+			},
+			expErr: true,
+		},
+		{
+			name: "error falls through on pipe",
+			args: map[string]string{
+				"module": "github.com/Azure/tool",
+				"proxy":  ts.URL + "/broken|" + ts.URL + "/proxy",
+			},
+			expVers: []string{"v0.9.0", "v1.0.0", "v1.1.0", "v2.0.0+incompatible"},
+		},
+		{
+			name: "not found",
+			args: map[string]string{
+				"module": "github.com/missing/module",
+			},
+			expErr: true,
+		},
+		{
+			name: "private",
+			args: map[string]string{
+				"module": "git.internal.example.com/team/tool",
+			},
+			expVers: []string{"v1.0.0"},
+		},
+		{
+			name: "off",
+			args: map[string]string{
+				"module": "github.com/Azure/tool",
+				"proxy":  "off",
+			},
+			expErr: true,
+		},
+		{
+			name:   "missing module",
+			args:   map[string]string{},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := newGoMod(config.Source{
+				Name: tc.name,
+				Type: "gomod",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != len(tc.expVers) {
+				t.Errorf("unexpected results, expected %v, received %v", tc.expVers, res.VerMap)
+			}
+			for _, ver := range tc.expVers {
+				if res.VerMap[ver] != ver {
+					t.Errorf("missing version %s in %v", ver, res.VerMap)
+				}
+				info, ok := res.VerMeta[ver].(*GoModInfo)
+				if !ok || info.Version != ver {
+					t.Errorf("unexpected metadata for %s: %v", ver, res.VerMeta[ver])
+					continue
+				}
+				if tm, err := info.Time(); err != nil || tm.IsZero() {
+					t.Errorf("unexpected time for %s: %v, %v", ver, tm, err)
+				}
+			}
+		})
+	}
+
+	t.Run("lazy info", func(t *testing.T) {
+		infoCount.Store(0)
+		res, err := newGoMod(config.Source{
+			Name: "lazy info",
+			Type: "gomod",
+			Args: map[string]string{"module": "example.com/badinfo"},
+		})
+		if err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		if len(res.VerMap) != 2 {
+			t.Errorf("unexpected results: %v", res.VerMap)
+		}
+		if n := infoCount.Load(); n != 0 {
+			t.Errorf("info requested before use, %d requests", n)
+		}
+		info, ok := res.VerMeta["v1.0.0"].(*GoModInfo)
+		if !ok {
+			t.Fatalf("unexpected metadata: %v", res.VerMeta["v1.0.0"])
+		}
+		if tm, err := info.Time(); err != nil || tm.IsZero() {
+			t.Errorf("unexpected time: %v, %v", tm, err)
+		}
+		if _, err := info.Time(); err != nil {
+			t.Errorf("failed on second request: %v", err)
+		}
+		if n := infoCount.Load(); n != 1 {
+			t.Errorf("unexpected number of info requests, expected 1, received %d", n)
+		}
+		info, ok = res.VerMeta["v1.1.0"].(*GoModInfo)
+		if !ok {
+			t.Fatalf("unexpected metadata: %v", res.VerMeta["v1.1.0"])
+		}
+		if _, err := info.Time(); err == nil {
+			t.Errorf("info did not fail")
+		}
+	})
+
+	t.Run("cache", func(t *testing.T) {
+		CacheSetup(CacheOpts{Dir: t.TempDir(), TTL: time.Hour})
+		t.Cleanup(func() { CacheSetup(CacheOpts{}) })
+		src := config.Source{
+			Name: "cache",
+			Type: "gomod",
+			Args: map[string]string{"module": "gopkg.in/yaml.v3"},
+		}
+		res, err := newGoMod(src)
+		if err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		if err := cachePut(src, res); err != nil {
+			t.Fatalf("failed to cache results: %v", err)
+		}
+		cached, ok := cacheGet(src, time.Hour)
+		if !ok {
+			t.Fatalf("cached results not found")
+		}
+		info, ok := cached.VerMeta["v3.0.1"].(*GoModInfo)
+		if !ok || info.Version != "v3.0.1" {
+			t.Fatalf("unexpected cached metadata: %v", cached.VerMeta["v3.0.1"])
+		}
+		if tm, err := info.Time(); err != nil || tm.IsZero() {
+			t.Errorf("unexpected cached time: %v, %v", tm, err)
+		}
+	})
+
+	t.Run("cache credentials", func(t *testing.T) {
+		CacheSetup(CacheOpts{Dir: t.TempDir(), TTL: time.Hour})
+		t.Cleanup(func() { CacheSetup(CacheOpts{}) })
+		t.Setenv("TEST_GOMOD_TOKEN", "secret")
+		src := config.Source{
+			Name: "cache credentials",
+			Type: "gomod",
+			Args: map[string]string{
+				"module":   "gopkg.in/yaml.v3",
+				"proxy":    ts.URL + "/auth",
+				"tokenEnv": "TEST_GOMOD_TOKEN",
+			},
+		}
+		if _, err := Get(src); err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		// the token is looked up again from the source config after loading from the cache
+		cached, ok := cacheGet(src, time.Hour)
+		if !ok {
+			t.Fatalf("cached results not found")
+		}
+		info, ok := cached.VerMeta["v3.0.1"].(*GoModInfo)
+		if !ok {
+			t.Fatalf("unexpected cached metadata: %v", cached.VerMeta["v3.0.1"])
+		}
+		infoCount.Store(0)
+		if tm, err := info.Time(); err != nil || tm.IsZero() {
+			t.Errorf("unexpected cached time: %v, %v", tm, err)
+		}
+		// after a flush, the loaded time is included in the cache
+		if err := CacheFlush(); err != nil {
+			t.Fatalf("failed to flush cache: %v", err)
+		}
+		cached, ok = cacheGet(src, time.Hour)
+		if !ok {
+			t.Fatalf("cached results not found after flush")
+		}
+		info, ok = cached.VerMeta["v3.0.1"].(*GoModInfo)
+		if !ok {
+			t.Fatalf("unexpected cached metadata: %v", cached.VerMeta["v3.0.1"])
+		}
+		if tm, err := info.Time(); err != nil || tm.IsZero() {
+			t.Errorf("unexpected flushed time: %v, %v", tm, err)
+		}
+		if n := infoCount.Load(); n != 1 {
+			t.Errorf("unexpected number of info requests, expected 1, received %d", n)
+		}
+	})
+}
+
+func TestGoModProxies(t *testing.T) {
+	t.Setenv("GONOPROXY", "")
+	t.Setenv("GOPRIVATE", "*.internal.example.com")
+	tests := []struct {
+		name     string
+		goproxy  string
+		args     map[string]string
+		expErr   bool
+		expProxy []gomodProxy
+	}{
+		{
+			name:    "default",
+			goproxy: "",
+			args:    map[string]string{"module": "github.com/org/proj"},
+			expProxy: []gomodProxy{
+				{url: "https://proxy.golang.org"},
+				{url: "direct"},
+			},
+		},
+		{
+			name:    "private skips public and direct",
+			goproxy: "https://proxy.golang.org|https://athens.example.com/,direct",
+			args:    map[string]string{"module": "git.internal.example.com/team/tool"},
+			expProxy: []gomodProxy{
+				{url: "https://athens.example.com"},
+			},
+		},
+		{
+			name:    "private without proxy",
+			goproxy: "https://proxy.golang.org,direct",
+			args:    map[string]string{"module": "git.internal.example.com/team/tool"},
+			expErr:  true,
+		},
+		{
+			name:    "private default",
+			goproxy: "",
+			args:    map[string]string{"module": "git.internal.example.com/team/tool"},
+			expErr:  true,
+		},
+		{
+			name:    "proxy arg ignores private",
+			goproxy: "",
+			args: map[string]string{
+				"module": "git.internal.example.com/team/tool",
+				"proxy":  "direct",
+			},
+			expProxy: []gomodProxy{
+				{url: "direct"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GOPROXY", tc.goproxy)
+			proxies, err := gomodProxies(config.Source{Args: tc.args}, tc.args["module"])
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail, received %v", proxies)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if !slices.Equal(proxies, tc.expProxy) {
+				t.Errorf("unexpected proxies, expected %v, received %v", tc.expProxy, proxies)
+			}
+		})
+	}
+}
+
+func TestGoModMatchPrefix(t *testing.T) {
+	tests := []struct {
+		patterns string
+		module   string
+		expect   bool
+	}{
+		{patterns: "", module: "github.com/org/repo", expect: false},
+		{patterns: "github.com/org", module: "github.com/org/repo/v2", expect: true},
+		{patterns: "github.com/org", module: "github.com/organization/repo", expect: false},
+		{patterns: "*.corp.example.com", module: "git.corp.example.com/repo", expect: true},
+		{patterns: "example.com/a, github.com/org/*", module: "github.com/org/repo", expect: true},
+		{patterns: "github.com/org/repo/sub", module: "github.com/org/repo", expect: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.patterns+"/"+tc.module, func(t *testing.T) {
+			if result := gomodMatchPrefix(tc.patterns, tc.module); result != tc.expect {
+				t.Errorf("unexpected result, expected %t, received %t", tc.expect, result)
+			}
+		})
+	}
+}
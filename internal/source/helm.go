@@ -0,0 +1,277 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/ref"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+const (
+	helmArgRepo    = "repo"
+	helmArgChart   = "chart"
+	helmArgDetails = "details"
+	helmOCIPrefix  = "oci://"
+	helmIndexFile  = "index.yaml"
+)
+
+var helmState struct {
+	once       sync.Once
+	httpClient *http.Client
+	mu         sync.Mutex // mutex for cache access
+	cache      map[string]*Results
+}
+
+func init() {
+	gob.Register(&HelmChart{})
+}
+
+// HelmChart is the metadata for a version of a chart.
+type HelmChart struct {
+	Name        string
+	Version     string
+	AppVersion  string
+	Digest      string // Digest is the chart archive digest from index.yaml, or the manifest digest for OCI charts
+	Created     time.Time
+	Deprecated  bool
+	Description string
+	URLs        []string
+}
+
+// helmIndex is the content of a chart repository index.yaml.
+type helmIndex struct {
+	Entries map[string][]struct {
+		Name        string   `yaml:"name"`
+		Version     string   `yaml:"version"`
+		AppVersion  string   `yaml:"appVersion"`
+		Digest      string   `yaml:"digest"`
+		Created     string   `yaml:"created"`
+		Deprecated  bool     `yaml:"deprecated"`
+		Description string   `yaml:"description"`
+		URLs        []string `yaml:"urls"`
+	} `yaml:"entries"`
+}
+
+// helmOCIConfig is the config blob of a chart pushed to an OCI registry.
+type helmOCIConfig struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	AppVersion  string `json:"appVersion"`
+	Deprecated  bool   `json:"deprecated"`
+	Description string `json:"description"`
+}
+
+// newHelm lists the versions of a chart from a chart repository index.yaml, or from an OCI registry.
+// OCI charts include the manifest digest of each version.
+// The appVersion, deprecated, and description of OCI charts are only included with the details arg,
+// which fetches the config blob of every version.
+func newHelm(conf config.Source) (Results, error) {
+	repo, ok := conf.Args[helmArgRepo]
+	if !ok || repo == "" {
+		return Results{}, fmt.Errorf("repo argument is required")
+	}
+	chart, ok := conf.Args[helmArgChart]
+	if !ok || chart == "" {
+		return Results{}, fmt.Errorf("chart argument is required")
+	}
+	details := false
+	if val, ok := conf.Args[helmArgDetails]; ok {
+		var err error
+		details, err = strconv.ParseBool(val)
+		if err != nil {
+			return Results{}, fmt.Errorf("details must be a bool value: \"%s\": %w", val, err)
+		}
+	}
+	helmState.once.Do(func() {
+		helmState.httpClient = http.DefaultClient
+		helmState.cache = map[string]*Results{}
+	})
+	key := fmt.Sprintf("%s:%s:%t", repo, chart, details)
+	helmState.mu.Lock()
+	defer helmState.mu.Unlock()
+	if r, ok := helmState.cache[key]; ok {
+		return *r, nil
+	}
+	var res Results
+	var err error
+	if strings.Contains(repo, "://") && !strings.HasPrefix(repo, "http://") && !strings.HasPrefix(repo, "https://") {
+		res, err = helmOCI(repo, chart, details)
+	} else {
+		res, err = helmRepoIndex(conf, repo, chart)
+	}
+	if err != nil {
+		return Results{}, err
+	}
+	if len(res.VerMap) == 0 {
+		return Results{}, fmt.Errorf("no versions found for chart %s in %s", chart, repo)
+	}
+	helmState.cache[key] = &res
+	return res, nil
+}
+
+// helmRepoIndex returns the versions of a chart from the index.yaml of a chart repository.
+func helmRepoIndex(conf config.Source, repo, chart string) (Results, error) {
+	u := repo
+	if !strings.HasSuffix(u, ".yaml") {
+		u = strings.TrimSuffix(u, "/") + "/" + helmIndexFile
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to parse repo url %s: %w", u, err)
+	}
+	auth, err := newHTTPAuth(conf, parsed)
+	if err != nil {
+		return Results{}, err
+	}
+	auth.bearer = true
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	auth.set(req)
+	//#nosec G704 repo URL is controlled by user running the command
+	resp, err := helmState.httpClient.Do(req)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to request %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to read response from %s: %w", u, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Results{}, fmt.Errorf("unexpected status from %s, status: %d, body: %s", u, resp.StatusCode, string(b))
+	}
+	index := helmIndex{}
+	if err := yaml.Unmarshal(b, &index); err != nil {
+		return Results{}, fmt.Errorf("failed to parse %s: %w", u, err)
+	}
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	for _, entry := range index.Entries[chart] {
+		if entry.Version == "" {
+			continue
+		}
+		meta := &HelmChart{
+			Name:        entry.Name,
+			Version:     entry.Version,
+			AppVersion:  entry.AppVersion,
+			Digest:      entry.Digest,
+			Deprecated:  entry.Deprecated,
+			Description: entry.Description,
+			URLs:        entry.URLs,
+		}
+		if t, err := time.Parse(time.RFC3339Nano, entry.Created); err == nil {
+			meta.Created = t
+		}
+		res.VerMap[entry.Version] = entry.Version
+		res.VerMeta[entry.Version] = meta
+	}
+	return res, nil
+}
+
+// helmOCI returns the versions of a chart from the tags of an OCI repository.
+// The repo may use oci:// for a registry, or any other scheme supported by regclient (e.g. ocidir://).
+func helmOCI(repo, chart string, details bool) (Results, error) {
+	rc := regSetup()
+	repoName := strings.TrimSuffix(strings.TrimPrefix(repo, helmOCIPrefix), "/") + "/" + chart
+	repoRef, err := ref.New(repoName)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to parse repo %s: %w", repoName, err)
+	}
+	ctx := context.Background()
+	defer rc.Close(ctx, repoRef)
+	tags, err := rc.TagList(ctx, repoRef)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to list tags: %w", err)
+	}
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	for _, tag := range tags.Tags {
+		// OCI tags cannot contain a "+", so helm replaces the semver build separator with "_"
+		ver := strings.ReplaceAll(tag, "_", "+")
+		meta := &HelmChart{
+			Name:    chart,
+			Version: ver,
+		}
+		if details {
+			if err := helmOCIDetails(ctx, rc, repoRef.SetTag(tag), meta); err != nil {
+				return Results{}, err
+			}
+		} else {
+			m, err := rc.ManifestHead(ctx, repoRef.SetTag(tag), regclient.WithManifestRequireDigest())
+			if err != nil {
+				return Results{}, fmt.Errorf("failed to get manifest %s: %w", repoRef.SetTag(tag).CommonName(), err)
+			}
+			meta.Digest = m.GetDescriptor().Digest.String()
+		}
+		res.VerMap[ver] = ver
+		res.VerMeta[ver] = meta
+	}
+	return res, nil
+}
+
+// helmOCIDetails adds the manifest digest and the chart config to the metadata.
+func helmOCIDetails(ctx context.Context, rc *regclient.RegClient, r ref.Ref, meta *HelmChart) error {
+	m, err := rc.ManifestGet(ctx, r)
+	if err != nil {
+		return fmt.Errorf("failed to get manifest %s: %w", r.CommonName(), err)
+	}
+	meta.Digest = m.GetDescriptor().Digest.String()
+	mi, ok := m.(manifest.Imager)
+	if !ok {
+		return fmt.Errorf("manifest is not a chart: %s", r.CommonName())
+	}
+	cd, err := mi.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get config descriptor %s: %w", r.CommonName(), err)
+	}
+	br, err := rc.BlobGet(ctx, r, cd)
+	if err != nil {
+		return fmt.Errorf("failed to get config %s: %w", r.CommonName(), err)
+	}
+	defer br.Close()
+	hc := helmOCIConfig{}
+	if err := json.NewDecoder(br).Decode(&hc); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", r.CommonName(), err)
+	}
+	if hc.Name != "" {
+		meta.Name = hc.Name
+	}
+	meta.AppVersion = hc.AppVersion
+	meta.Deprecated = hc.Deprecated
+	meta.Description = hc.Description
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
+	"github.com/regclient/regclient/types/oci"
+	v1 "github.com/regclient/regclient/types/oci/v1"
+	"github.com/regclient/regclient/types/ref"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestHelm(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /charts/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`apiVersion: v1
+entries:
+  app:
+  - name: app
+    version: 1.2.0
+    appVersion: "4.1"
+    digest: 1111111111111111111111111111111111111111111111111111111111111111
+    created: "2024-03-01T10:00:00.123456789Z"
+    urls:
+    - https://example.com/charts/app-1.2.0.tgz
+  - name: app
+    version: 1.1.0
+    appVersion: "4.0"
+    deprecated: true
+    digest: 2222222222222222222222222222222222222222222222222222222222222222
+    created: "2024-01-01T10:00:00Z"
+  other:
+  - name: other
+    version: 9.9.9
+generated: "2024-03-01T10:00:00Z"
+`))
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	// push a chart to an OCI Layout for each version
+	ociDir := t.TempDir()
+	ctx := context.Background()
+	rc := regSetup()
+	for _, ver := range []string{"0.1.0", "0.2.0+build.1"} {
+		r, err := ref.New("ocidir://" + ociDir + "/app:" + strings.ReplaceAll(ver, "+", "_"))
+		if err != nil {
+			t.Fatalf("failed to parse ref: %v", err)
+		}
+		cd, err := rc.BlobPut(ctx, r, descriptor.Descriptor{MediaType: "application/vnd.cncf.helm.config.v1+json"},
+			bytes.NewReader([]byte(`{"name":"app","version":"`+ver+`","appVersion":"app-`+ver+`"}`)))
+		if err != nil {
+			t.Fatalf("failed to push config: %v", err)
+		}
+		ld, err := rc.BlobPut(ctx, r, descriptor.Descriptor{MediaType: "application/vnd.cncf.helm.chart.content.v1.tar+gzip"},
+			bytes.NewReader([]byte("chart "+ver)))
+		if err != nil {
+			t.Fatalf("failed to push layer: %v", err)
+		}
+		m, err := manifest.New(manifest.WithOrig(v1.Manifest{
+			Versioned: oci.Versioned{SchemaVersion: 2},
+			MediaType: mediatype.OCI1Manifest,
+			Config:    cd,
+			Layers:    []descriptor.Descriptor{ld},
+		}))
+		if err != nil {
+			t.Fatalf("failed to create manifest: %v", err)
+		}
+		if err := rc.ManifestPut(ctx, r, m); err != nil {
+			t.Fatalf("failed to push manifest: %v", err)
+		}
+		if err := rc.Close(ctx, r); err != nil {
+			t.Fatalf("failed to close ref: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		args       map[string]string
+		expErr     bool
+		expVers    []string
+		expVer     string
+		expApp     string
+		expDigest  bool
+		expDeprec  bool
+		expCreated bool
+	}{
+		{
+			name: "index",
+			args: map[string]string{
+				"repo":  ts.URL + "/charts/",
+				"chart": "app",
+			},
+			expVers:    []string{"1.1.0", "1.2.0"},
+			expVer:     "1.2.0",
+			expApp:     "4.1",
+			expDigest:  true,
+			expCreated: true,
+		},
+		{
+			name: "index deprecated",
+			args: map[string]string{
+				"repo":  ts.URL + "/charts/index.yaml",
+				"chart": "app",
+			},
+			expVers:    []string{"1.1.0", "1.2.0"},
+			expVer:     "1.1.0",
+			expApp:     "4.0",
+			expDigest:  true,
+			expDeprec:  true,
+			expCreated: true,
+		},
+		{
+			name: "oci",
+			args: map[string]string{
+				"repo":  "ocidir://" + ociDir,
+				"chart": "app",
+			},
+			expVers:   []string{"0.1.0", "0.2.0+build.1"},
+			expVer:    "0.2.0+build.1",
+			expDigest: true,
+		},
+		{
+			name: "oci details",
+			args: map[string]string{
+				"repo":    "ocidir://" + ociDir + "/",
+				"chart":   "app",
+				"details": "true",
+			},
+			expVers:   []string{"0.1.0", "0.2.0+build.1"},
+			expVer:    "0.2.0+build.1",
+			expApp:    "app-0.2.0+build.1",
+			expDigest: true,
+		},
+		{
+			name: "missing chart",
+			args: map[string]string{
+				"repo":  ts.URL + "/charts",
+				"chart": "missing",
+			},
+			expErr: true,
+		},
+		{
+			name: "missing repo",
+			args: map[string]string{
+				"repo":  ts.URL + "/missing",
+				"chart": "app",
+			},
+			expErr: true,
+		},
+		{
+			name: "missing args",
+			args: map[string]string{
+				"repo": ts.URL + "/charts",
+			},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := newHelm(config.Source{
+				Name: tc.name,
+				Type: "helm",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != len(tc.expVers) {
+				t.Errorf("unexpected results, expected %v, received %v", tc.expVers, res.VerMap)
+			}
+			for _, ver := range tc.expVers {
+				if res.VerMap[ver] != ver {
+					t.Errorf("missing version %s in %v", ver, res.VerMap)
+				}
+			}
+			meta, ok := res.VerMeta[tc.expVer].(*HelmChart)
+			if !ok {
+				t.Fatalf("missing metadata for %s", tc.expVer)
+			}
+			if meta.Name != "app" || meta.Version != tc.expVer || meta.AppVersion != tc.expApp ||
+				(meta.Digest != "") != tc.expDigest || meta.Deprecated != tc.expDeprec || meta.Created.IsZero() == tc.expCreated {
+				t.Errorf("unexpected metadata for %s: %v", tc.expVer, *meta)
+			}
+		})
+	}
+}
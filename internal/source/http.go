@@ -0,0 +1,248 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+const (
+	httpArgTokenEnv     = "tokenEnv"
+	httpArgTokenFile    = "tokenFile"
+	httpArgPerPage      = "perPage"
+	httpArgMaxPages     = "maxPages"
+	httpDefaultPerPage  = 100
+	httpDefaultMaxPages = 10
+)
+
+// httpAuth contains the credentials for requests to a single host.
+type httpAuth struct {
+	host     string
+	header   string // header for the token, defaults to the Authorization header
+	bearer   bool   // bearer sends the token as a bearer token, otherwise the token is the password for basic auth
+	token    string
+	login    string
+	password string
+}
+
+// newHTTPAuth looks up the credentials for a host.
+// A token is read from the env var or file named in the source args, falling back to each of the defEnv vars.
+// Without a token, the login and password for the host are read from the netrc file.
+func newHTTPAuth(conf config.Source, u *url.URL, defEnv ...string) (*httpAuth, error) {
+	a := &httpAuth{host: u.Host}
+	if name := conf.Args[httpArgTokenEnv]; name != "" {
+		a.token = os.Getenv(name)
+	}
+	if file := conf.Args[httpArgTokenFile]; a.token == "" && file != "" {
+		//#nosec G304 file to read is controlled by user running the command
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token file: %w", err)
+		}
+		a.token = strings.TrimSpace(string(b))
+	}
+	for _, name := range defEnv {
+		if a.token != "" {
+			break
+		}
+		a.token = os.Getenv(name)
+	}
+	if a.token == "" {
+		a.login, a.password, _ = netrcLookup(u.Hostname())
+	}
+	return a, nil
+}
+
+// set adds the credentials to a request, only when the request is for the same host.
+func (a *httpAuth) set(req *http.Request) {
+	if a == nil || req.URL.Host != a.host {
+		return
+	}
+	if a.token != "" && a.header != "" {
+		req.Header.Set(a.header, a.token)
+	} else if a.token != "" && a.bearer {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	} else if a.token != "" {
+		// the login is ignored by GitHub when the password is a token
+		req.SetBasicAuth("git", a.token)
+	} else if a.login != "" || a.password != "" {
+		req.SetBasicAuth(a.login, a.password)
+	}
+}
+
+// netrcLookup returns the login and password for a host from $NETRC or ~/.netrc.
+func netrcLookup(host string) (string, string, bool) {
+	file := os.Getenv("NETRC")
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false
+		}
+		file = filepath.Join(home, ".netrc")
+	}
+	//#nosec G304 file to read is controlled by user running the command
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", "", false
+	}
+	return netrcParse(string(b), host)
+}
+
+// netrcParse returns the login and password for the matching machine, or the default entry.
+func netrcParse(content, host string) (string, string, bool) {
+	type entry struct {
+		login, password string
+	}
+	var match, def *entry
+	var cur *entry
+	lines := strings.Split(content, "\n")
+	tokens := []string{}
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		tokens = append(tokens, strings.Fields(line)...)
+	}
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			cur = &entry{}
+			if i+1 < len(tokens) {
+				i++
+				if match == nil && tokens[i] == host {
+					match = cur
+				}
+			}
+		case "default":
+			cur = &entry{}
+			if def == nil {
+				def = cur
+			}
+		case "login", "password", "account", "port":
+			if i+1 >= len(tokens) {
+				break
+			}
+			i++
+			if cur == nil {
+				continue
+			}
+			switch tokens[i-1] {
+			case "login":
+				cur.login = tokens[i]
+			case "password":
+				cur.password = tokens[i]
+			}
+		}
+	}
+	if match == nil {
+		match = def
+	}
+	if match == nil {
+		return "", "", false
+	}
+	return match.login, match.password, true
+}
+
+// httpPageArgs returns the number of entries per page and the maximum number of pages to request.
+func httpPageArgs(conf config.Source) (int, int, error) {
+	perPage := httpDefaultPerPage
+	if val, ok := conf.Args[httpArgPerPage]; ok {
+		i, err := strconv.Atoi(val)
+		if err != nil || i < 1 || i > 100 {
+			return 0, 0, fmt.Errorf("perPage must be a number between 1 and 100: \"%s\"", val)
+		}
+		perPage = i
+	}
+	maxPages := httpDefaultMaxPages
+	if val, ok := conf.Args[httpArgMaxPages]; ok {
+		i, err := strconv.Atoi(val)
+		if err != nil || i < 0 {
+			return 0, 0, fmt.Errorf("maxPages must be a positive number, or 0 for no limit: \"%s\"", val)
+		}
+		maxPages = i
+	}
+	return perPage, maxPages, nil
+}
+
+// httpGetPages requests a list of entries from a JSON API, following the Link header to each next page.
+// A maxPages of 0 requests every page.
+func httpGetPages[T any](client *http.Client, u string, auth *httpAuth, maxPages int) ([]T, error) {
+	list := []T{}
+	for page := 0; u != "" && (maxPages <= 0 || page < maxPages); page++ {
+		req, err := http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Add("Accept", "application/json")
+		auth.set(req)
+		//#nosec G704 config file containing URL fragments is controlled by user running the command
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to call API: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			b, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status from API, status: %d, body: %s", resp.StatusCode, string(b))
+		}
+		entries := []T{}
+		err = json.NewDecoder(resp.Body).Decode(&entries)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode API response: %w", err)
+		}
+		list = append(list, entries...)
+		u, err = linkNext(resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// linkNext returns the url of the next page from the Link header, resolved against the request url.
+func linkNext(resp *http.Response) (string, error) {
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if k != "rel" || !slices.Contains(strings.Fields(strings.Trim(v, `"`)), "next") {
+					continue
+				}
+				u, err := resp.Request.URL.Parse(target[1 : len(target)-1])
+				if err != nil {
+					return "", fmt.Errorf("failed to parse next link %s: %w", target, err)
+				}
+				return u.String(), nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,113 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+func TestNetrcParse(t *testing.T) {
+	content := `# comment with machine example.com
+machine git.example.com login git password secret
+machine ghe.example.com
+  login user
+  account ignored
+  password token
+default login anonymous password guest
+`
+	tests := []struct {
+		host        string
+		expLogin    string
+		expPassword string
+	}{
+		{host: "git.example.com", expLogin: "git", expPassword: "secret"},
+		{host: "ghe.example.com", expLogin: "user", expPassword: "token"},
+		{host: "example.com", expLogin: "anonymous", expPassword: "guest"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.host, func(t *testing.T) {
+			login, password, ok := netrcParse(content, tc.host)
+			if !ok {
+				t.Fatalf("no entry found")
+			}
+			if login != tc.expLogin || password != tc.expPassword {
+				t.Errorf("unexpected entry, expected %s/%s, received %s/%s", tc.expLogin, tc.expPassword, login, password)
+			}
+		})
+	}
+	if _, _, ok := netrcParse("machine other login a password b\n", "example.com"); ok {
+		t.Errorf("unexpected entry found without a default")
+	}
+}
+
+func TestHTTPAuthSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    *httpAuth
+		url     string
+		expName string
+		expVal  string
+	}{
+		{
+			name:    "basic token",
+			auth:    &httpAuth{host: "example.com", token: "secret"},
+			url:     "https://example.com/api",
+			expName: "Authorization",
+			expVal:  "Basic " + base64.StdEncoding.EncodeToString([]byte("git:secret")),
+		},
+		{
+			name:    "bearer token",
+			auth:    &httpAuth{host: "example.com", token: "secret", bearer: true},
+			url:     "https://example.com/api",
+			expName: "Authorization",
+			expVal:  "Bearer secret",
+		},
+		{
+			name:    "header",
+			auth:    &httpAuth{host: "example.com", token: "secret", header: "PRIVATE-TOKEN", bearer: true},
+			url:     "https://example.com/api",
+			expName: "PRIVATE-TOKEN",
+			expVal:  "secret",
+		},
+		{
+			name:    "netrc",
+			auth:    &httpAuth{host: "example.com", login: "user", password: "pass", bearer: true},
+			url:     "https://example.com/api",
+			expName: "Authorization",
+			expVal:  "Basic " + base64.StdEncoding.EncodeToString([]byte("user:pass")),
+		},
+		{
+			name:    "other host",
+			auth:    &httpAuth{host: "example.com", token: "secret"},
+			url:     "https://example.org/api",
+			expName: "Authorization",
+			expVal:  "",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			tc.auth.set(req)
+			if val := req.Header.Get(tc.expName); val != tc.expVal {
+				t.Errorf("unexpected %s header, expected %s, received %s", tc.expName, tc.expVal, val)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+const (
+	npmArgPackage      = "package"
+	npmArgRegistry     = "registry"
+	npmDefaultRegistry = "https://registry.npmjs.org/"
+	npmEnvRegistry     = "NPM_CONFIG_REGISTRY"
+	npmEnvUserConfig   = "NPM_CONFIG_USERCONFIG"
+	npmrcFile          = ".npmrc"
+)
+
+var npmState struct {
+	once       sync.Once
+	httpClient *http.Client
+	mu         sync.Mutex // mutex for cache access
+	cache      map[string]*Results
+}
+
+func init() {
+	gob.Register(&NPMVersion{})
+}
+
+// NPMVersion is the metadata for a published version of an npm package.
+type NPMVersion struct {
+	Version           string
+	Time              time.Time
+	Deprecated        bool
+	DeprecatedMessage string
+	Tags              []string // dist-tags that point to this version
+	Tarball           string
+	Shasum            string
+	Integrity         string
+}
+
+// npmPackument is the package metadata returned by the registry.
+type npmPackument struct {
+	Name     string               `json:"name"`
+	DistTags map[string]string    `json:"dist-tags"`
+	Versions map[string]npmPkgVer `json:"versions"`
+	Time     map[string]string    `json:"time"`
+}
+
+type npmPkgVer struct {
+	Version    string `json:"version"`
+	Deprecated any    `json:"deprecated"` // usually a message, but some registries use a bool
+	Dist       struct {
+		Tarball   string `json:"tarball"`
+		Shasum    string `json:"shasum"`
+		Integrity string `json:"integrity"`
+	} `json:"dist"`
+}
+
+// newNPM lists the versions and dist-tags of a package from an npm registry.
+// Each version maps to itself, and each dist-tag (e.g. latest) maps to the tagged version.
+func newNPM(conf config.Source) (Results, error) {
+	pkg, ok := conf.Args[npmArgPackage]
+	if !ok || pkg == "" {
+		return Results{}, fmt.Errorf("package argument is required")
+	}
+	npmState.once.Do(func() {
+		npmState.httpClient = http.DefaultClient
+		npmState.cache = map[string]*Results{}
+	})
+	rc := npmrcLoad()
+	registry := npmRegistry(conf, rc, pkg)
+	key := registry + ":" + pkg
+	npmState.mu.Lock()
+	defer npmState.mu.Unlock()
+	if r, ok := npmState.cache[key]; ok {
+		return *r, nil
+	}
+	// scoped packages escape the slash, e.g. @scope%2fname
+	u, err := url.Parse(registry + strings.Replace(pkg, "/", "%2f", 1))
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to parse registry url %s: %w", registry, err)
+	}
+	auth, err := newHTTPAuth(conf, u)
+	if err != nil {
+		return Results{}, err
+	}
+	auth.bearer = true
+	if auth.token == "" {
+		npmrcAuth(rc, u, auth)
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	// the full document is needed for the publish times
+	req.Header.Set("Accept", "application/json")
+	auth.set(req)
+	//#nosec G704 registry URL is controlled by user running the command
+	resp, err := npmState.httpClient.Do(req)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to request %s: %w", u.String(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return Results{}, fmt.Errorf("unexpected status from %s, status: %d, body: %s", u.String(), resp.StatusCode, string(b))
+	}
+	doc := npmPackument{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return Results{}, fmt.Errorf("failed to parse package metadata from %s: %w", u.String(), err)
+	}
+	if len(doc.Versions) == 0 {
+		return Results{}, fmt.Errorf("no versions found for package %s", pkg)
+	}
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	for ver, pv := range doc.Versions {
+		meta := &NPMVersion{
+			Version:   ver,
+			Tarball:   pv.Dist.Tarball,
+			Shasum:    pv.Dist.Shasum,
+			Integrity: pv.Dist.Integrity,
+		}
+		switch dep := pv.Deprecated.(type) {
+		case string:
+			meta.Deprecated = dep != ""
+			meta.DeprecatedMessage = dep
+		case bool:
+			meta.Deprecated = dep
+		}
+		if t, err := time.Parse(time.RFC3339, doc.Time[ver]); err == nil {
+			meta.Time = t
+		}
+		res.VerMap[ver] = ver
+		res.VerMeta[ver] = meta
+	}
+	tags := make([]string, 0, len(doc.DistTags))
+	for tag := range doc.DistTags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		ver := doc.DistTags[tag]
+		meta, ok := res.VerMeta[ver].(*NPMVersion)
+		// skip dist-tags that conflict with a version or point to an unpublished version
+		if !ok || res.VerMap[tag] != "" {
+			continue
+		}
+		meta.Tags = append(meta.Tags, tag)
+		res.VerMap[tag] = ver
+		res.VerMeta[tag] = meta
+	}
+	npmState.cache[key] = &res
+	return res, nil
+}
+
+// npmRegistry returns the registry url with a trailing slash.
+// The registry arg is used first, followed by a scoped registry from the npmrc, the env, and the default registry from the npmrc.
+func npmRegistry(conf config.Source, rc map[string]string, pkg string) string {
+	registry := conf.Args[npmArgRegistry]
+	if scope, _, ok := strings.Cut(pkg, "/"); registry == "" && ok && strings.HasPrefix(scope, "@") {
+		registry = rc[scope+":registry"]
+	}
+	if registry == "" {
+		registry = os.Getenv(npmEnvRegistry)
+	}
+	if registry == "" {
+		registry = rc["registry"]
+	}
+	if registry == "" {
+		registry = npmDefaultRegistry
+	}
+	if !strings.HasSuffix(registry, "/") {
+		registry += "/"
+	}
+	return registry
+}
+
+// npmrcLoad reads the user npmrc followed by the npmrc in the current directory, expanding ${VAR} references to env vars.
+func npmrcLoad() map[string]string {
+	rc := map[string]string{}
+	files := []string{}
+	if file := os.Getenv(npmEnvUserConfig); file != "" {
+		files = append(files, file)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, npmrcFile))
+	}
+	files = append(files, npmrcFile)
+	for _, file := range files {
+		//#nosec G304 file to read is controlled by user running the command
+		b, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		npmrcParse(string(b), rc)
+	}
+	return rc
+}
+
+var npmrcEnvRe = regexp.MustCompile(`\$\{([^${}?]+)\??\}`)
+
+// npmrcParse adds the key=value entries to rc.
+func npmrcParse(content string, rc map[string]string) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		k = strings.TrimSpace(k)
+		v = strings.Trim(strings.TrimSpace(v), `"`)
+		v = npmrcEnvRe.ReplaceAllStringFunc(v, func(s string) string {
+			return os.Getenv(npmrcEnvRe.FindStringSubmatch(s)[1])
+		})
+		rc[k] = v
+	}
+}
+
+// npmrcAuth sets the token or basic auth from the npmrc entry with the longest matching prefix of the url.
+// Entries use the registry url without the scheme, e.g. //registry.example.com/path/:_authToken=secret
+func npmrcAuth(rc map[string]string, u *url.URL, auth *httpAuth) {
+	p := u.EscapedPath()
+	for {
+		i := strings.LastIndex(p, "/")
+		if i < 0 {
+			return
+		}
+		p = p[:i]
+		prefix := "//" + u.Host + p + "/:"
+		if token := rc[prefix+"_authToken"]; token != "" {
+			auth.token = token
+			auth.login, auth.password = "", ""
+			return
+		}
+		if basic := rc[prefix+"_auth"]; basic != "" {
+			if b, err := base64.StdEncoding.DecodeString(basic); err == nil {
+				if login, password, ok := strings.Cut(string(b), ":"); ok {
+					auth.login, auth.password = login, password
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestNPM(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /cli", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"name": "cli",
+			"dist-tags": {"latest": "1.2.0", "next": "2.0.0-beta.1", "legacy": "0.9.0"},
+			"versions": {
+				"1.0.0": {"version": "1.0.0", "deprecated": "security issue, upgrade to 1.2.0", "dist": {"tarball": "https://example.com/cli-1.0.0.tgz"}},
+				"1.2.0": {"version": "1.2.0", "dist": {"tarball": "https://example.com/cli-1.2.0.tgz", "integrity": "sha512-abc"}},
+				"2.0.0-beta.1": {"version": "2.0.0-beta.1", "deprecated": false}
+			},
+			"time": {
+				"created": "2023-01-01T00:00:00.000Z",
+				"1.0.0": "2023-01-01T00:00:00.000Z",
+				"1.2.0": "2023-06-01T12:30:00.000Z",
+				"2.0.0-beta.1": "2024-01-01T00:00:00.000Z"
+			}
+		}`))
+	})
+	mux.HandleFunc("GET /private/@team%2fcli", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer npm-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{
+			"name": "@team/cli",
+			"dist-tags": {"latest": "3.1.0"},
+			"versions": {"3.1.0": {"version": "3.1.0"}},
+			"time": {"3.1.0": "2024-02-03T04:05:06Z"}
+		}`))
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	tempDir := t.TempDir()
+	npmrc := filepath.Join(tempDir, "npmrc")
+	err := os.WriteFile(npmrc, []byte("; user config\n@team:registry="+ts.URL+"/private/\n//"+ts.Listener.Addr().String()+"/private/:_authToken=${TEST_NPM_TOKEN}\n"), 0o600)
+	if err != nil {
+		t.Fatalf("failed to write npmrc: %v", err)
+	}
+	t.Setenv("NPM_CONFIG_USERCONFIG", npmrc)
+	t.Setenv("NPM_CONFIG_REGISTRY", "")
+	t.Setenv("TEST_NPM_TOKEN", "npm-secret")
+
+	tests := []struct {
+		name      string
+		args      map[string]string
+		expErr    bool
+		expVerMap map[string]string
+	}{
+		{
+			name: "versions and tags",
+			args: map[string]string{
+				"package":  "cli",
+				"registry": ts.URL,
+			},
+			expVerMap: map[string]string{
+				"1.0.0":        "1.0.0",
+				"1.2.0":        "1.2.0",
+				"2.0.0-beta.1": "2.0.0-beta.1",
+				"latest":       "1.2.0",
+				"next":         "2.0.0-beta.1",
+			},
+		},
+		{
+			name: "scoped with npmrc",
+			args: map[string]string{
+				"package": "@team/cli",
+			},
+			expVerMap: map[string]string{
+				"3.1.0":  "3.1.0",
+				"latest": "3.1.0",
+			},
+		},
+		{
+			name: "missing package",
+			args: map[string]string{
+				"package":  "missing",
+				"registry": ts.URL + "/",
+			},
+			expErr: true,
+		},
+		{
+			name:   "missing arg",
+			args:   map[string]string{},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := newNPM(config.Source{
+				Name: tc.name,
+				Type: "npm",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != len(tc.expVerMap) {
+				t.Errorf("unexpected results, expected %v, received %v", tc.expVerMap, res.VerMap)
+			}
+			for k, v := range tc.expVerMap {
+				if res.VerMap[k] != v {
+					t.Errorf("unexpected value for %s, expected %s, received %s", k, v, res.VerMap[k])
+				}
+				if meta, ok := res.VerMeta[k].(*NPMVersion); !ok || meta.Version != v || meta.Time.IsZero() {
+					t.Errorf("unexpected metadata for %s: %v", k, res.VerMeta[k])
+				}
+			}
+		})
+	}
+
+	t.Run("metadata", func(t *testing.T) {
+		res, err := newNPM(config.Source{
+			Name: "metadata",
+			Type: "npm",
+			Args: tests[0].args,
+		})
+		if err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		old := res.VerMeta["1.0.0"].(*NPMVersion)
+		if !old.Deprecated || old.DeprecatedMessage != "security issue, upgrade to 1.2.0" {
+			t.Errorf("expected 1.0.0 to be deprecated: %v", old)
+		}
+		latest := res.VerMeta["latest"].(*NPMVersion)
+		if latest.Deprecated || latest.Integrity != "sha512-abc" || len(latest.Tags) != 1 || latest.Tags[0] != "latest" {
+			t.Errorf("unexpected metadata for latest: %v", latest)
+		}
+		if beta := res.VerMeta["2.0.0-beta.1"].(*NPMVersion); beta.Deprecated {
+			t.Errorf("expected beta to not be deprecated: %v", beta)
+		}
+	})
+}
@@ -0,0 +1,340 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+const (
+	pypiArgPackage      = "package"
+	pypiArgIndex        = "index"
+	pypiArgAPI          = "api"
+	pypiArgAllowYanked  = "allowYanked"
+	pypiAPIJSON         = "json"
+	pypiAPISimple       = "simple"
+	pypiDefaultJSON     = "https://pypi.org"
+	pypiDefaultSimple   = "https://pypi.org/simple"
+	pypiMediaSimpleJSON = "application/vnd.pypi.simple.v1+json"
+)
+
+var pypiState struct {
+	once       sync.Once
+	httpClient *http.Client
+	mu         sync.Mutex // mutex for cache access
+	cache      map[string]*Results
+}
+
+func init() {
+	gob.Register(&PyPIRelease{})
+}
+
+// PyPIRelease is the metadata for a release, combined from each of the uploaded files.
+type PyPIRelease struct {
+	Version        string
+	UploadTime     time.Time // UploadTime is the time of the first file uploaded
+	RequiresPython string
+	Yanked         bool // Yanked is true when every file in the release is yanked
+	YankedReason   string
+}
+
+// pypiFile is a single uploaded file, from either API.
+type pypiFile struct {
+	version        string
+	uploadTime     time.Time
+	requiresPython string
+	yanked         bool
+	yankedReason   string
+}
+
+// newPyPI lists the releases of a package from a PyPI compatible index.
+// The api may be json for the PyPI JSON API, or simple for the simple repository API in either JSON or HTML.
+func newPyPI(conf config.Source) (Results, error) {
+	pkg, ok := conf.Args[pypiArgPackage]
+	if !ok || pkg == "" {
+		return Results{}, fmt.Errorf("package argument is required")
+	}
+	api := conf.Args[pypiArgAPI]
+	if api == "" {
+		api = pypiAPIJSON
+	}
+	index := strings.TrimSuffix(conf.Args[pypiArgIndex], "/")
+	switch {
+	case api == pypiAPIJSON && index == "":
+		index = pypiDefaultJSON
+	case api == pypiAPISimple && index == "":
+		index = pypiDefaultSimple
+	case api != pypiAPIJSON && api != pypiAPISimple:
+		return Results{}, fmt.Errorf("unsupported api: %s", api)
+	}
+	allowYanked := false
+	if val, ok := conf.Args[pypiArgAllowYanked]; ok {
+		var err error
+		allowYanked, err = strconv.ParseBool(val)
+		if err != nil {
+			return Results{}, fmt.Errorf("allowYanked must be a bool value: \"%s\": %w", val, err)
+		}
+	}
+	pypiState.once.Do(func() {
+		pypiState.httpClient = http.DefaultClient
+		pypiState.cache = map[string]*Results{}
+	})
+	key := fmt.Sprintf("%s:%s:%s:%t", api, index, pkg, allowYanked)
+	pypiState.mu.Lock()
+	defer pypiState.mu.Unlock()
+	if r, ok := pypiState.cache[key]; ok {
+		return *r, nil
+	}
+	var files []pypiFile
+	var err error
+	if api == pypiAPIJSON {
+		files, err = pypiJSON(conf, index+"/pypi/"+url.PathEscape(pkg)+"/json")
+	} else {
+		files, err = pypiSimple(conf, index+"/"+pypiNormalize(pkg)+"/", pkg)
+	}
+	if err != nil {
+		return Results{}, err
+	}
+	releases := map[string]*PyPIRelease{}
+	for _, f := range files {
+		r, ok := releases[f.version]
+		if !ok {
+			r = &PyPIRelease{Version: f.version, Yanked: true}
+			releases[f.version] = r
+		}
+		if !f.uploadTime.IsZero() && (r.UploadTime.IsZero() || f.uploadTime.Before(r.UploadTime)) {
+			r.UploadTime = f.uploadTime
+		}
+		if r.RequiresPython == "" {
+			r.RequiresPython = f.requiresPython
+		}
+		if f.yanked && r.YankedReason == "" {
+			r.YankedReason = f.yankedReason
+		}
+		r.Yanked = r.Yanked && f.yanked
+	}
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	for ver, r := range releases {
+		if r.Yanked && !allowYanked {
+			continue
+		}
+		if !r.Yanked {
+			r.YankedReason = ""
+		}
+		res.VerMap[ver] = ver
+		res.VerMeta[ver] = r
+	}
+	if len(res.VerMap) == 0 {
+		return Results{}, fmt.Errorf("no releases found for package %s", pkg)
+	}
+	pypiState.cache[key] = &res
+	return res, nil
+}
+
+// pypiJSON returns the files from the PyPI JSON API.
+func pypiJSON(conf config.Source, u string) ([]pypiFile, error) {
+	b, _, err := pypiGet(conf, u, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	doc := struct {
+		Releases map[string][]struct {
+			UploadTime     time.Time `json:"upload_time_iso_8601"`
+			RequiresPython string    `json:"requires_python"`
+			Yanked         bool      `json:"yanked"`
+			YankedReason   string    `json:"yanked_reason"`
+		} `json:"releases"`
+	}{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", u, err)
+	}
+	files := []pypiFile{}
+	// releases without any files cannot be installed and are skipped
+	for ver, list := range doc.Releases {
+		for _, f := range list {
+			files = append(files, pypiFile{
+				version:        ver,
+				uploadTime:     f.UploadTime,
+				requiresPython: f.RequiresPython,
+				yanked:         f.Yanked,
+				yankedReason:   f.YankedReason,
+			})
+		}
+	}
+	return files, nil
+}
+
+var pypiAnchorRE = regexp.MustCompile(`(?is)<a\s([^>]*)>(.*?)</a>`)
+var pypiAttrRE = regexp.MustCompile(`(?is)([a-z0-9-]+)\s*=\s*(?:"([^"]*)"|'([^']*)')|([a-z0-9-]+)`)
+
+// pypiSimple returns the files from the simple repository API, using the JSON format when the index supports it.
+func pypiSimple(conf config.Source, u, pkg string) ([]pypiFile, error) {
+	b, contentType, err := pypiGet(conf, u, pypiMediaSimpleJSON+", text/html;q=0.1")
+	if err != nil {
+		return nil, err
+	}
+	files := []pypiFile{}
+	if mt, _, _ := mime.ParseMediaType(contentType); mt == pypiMediaSimpleJSON {
+		doc := struct {
+			Files []struct {
+				Filename       string    `json:"filename"`
+				RequiresPython string    `json:"requires-python"`
+				Yanked         any       `json:"yanked"` // bool or a string with the reason
+				UploadTime     time.Time `json:"upload-time"`
+			} `json:"files"`
+		}{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", u, err)
+		}
+		for _, f := range doc.Files {
+			ver, ok := pypiFileVersion(f.Filename, pkg)
+			if !ok {
+				continue
+			}
+			file := pypiFile{
+				version:        ver,
+				uploadTime:     f.UploadTime,
+				requiresPython: f.RequiresPython,
+			}
+			switch y := f.Yanked.(type) {
+			case bool:
+				file.yanked = y
+			case string:
+				file.yanked = true
+				file.yankedReason = y
+			}
+			files = append(files, file)
+		}
+		return files, nil
+	}
+	// HTML: <a href="..." data-requires-python="&gt;=3.8" data-yanked="reason">pkg-1.0.tar.gz</a>
+	for _, m := range pypiAnchorRE.FindAllStringSubmatch(string(b), -1) {
+		ver, ok := pypiFileVersion(strings.TrimSpace(html.UnescapeString(m[2])), pkg)
+		if !ok {
+			continue
+		}
+		file := pypiFile{version: ver}
+		for _, attr := range pypiAttrRE.FindAllStringSubmatch(m[1], -1) {
+			name := strings.ToLower(attr[1] + attr[4])
+			val := html.UnescapeString(attr[2] + attr[3])
+			switch name {
+			case "data-requires-python":
+				file.requiresPython = val
+			case "data-yanked":
+				file.yanked = true
+				file.yankedReason = val
+			}
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// pypiGet returns the response body and content type.
+func pypiGet(conf config.Source, u, accept string) ([]byte, string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse url %s: %w", u, err)
+	}
+	auth, err := newHTTPAuth(conf, parsed)
+	if err != nil {
+		return nil, "", err
+	}
+	auth.bearer = true
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", accept)
+	auth.set(req)
+	//#nosec G704 index URL is controlled by user running the command
+	resp, err := pypiState.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to request %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response from %s: %w", u, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status from %s, status: %d, body: %s", u, resp.StatusCode, string(b))
+	}
+	return b, resp.Header.Get("Content-Type"), nil
+}
+
+var pypiNormalizeRE = regexp.MustCompile(`[-_.]+`)
+
+// pypiNormalize returns the normalized project name from PEP 503.
+func pypiNormalize(name string) string {
+	return strings.ToLower(pypiNormalizeRE.ReplaceAllString(name, "-"))
+}
+
+// pypiFileVersion returns the version from the filename of a wheel, egg, or source distribution.
+func pypiFileVersion(filename, pkg string) (string, bool) {
+	norm := pypiNormalize(pkg)
+	lower := strings.ToLower(filename)
+	var rest string
+	found := false
+	// the project name may contain dashes in older source distributions
+	for i := 0; i < len(filename); i++ {
+		if filename[i] == '-' && pypiNormalize(filename[:i]) == norm {
+			rest = filename[i+1:]
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", false
+	}
+	switch {
+	case strings.HasSuffix(lower, ".whl"), strings.HasSuffix(lower, ".egg"):
+		// name-ver(-build)?-python-abi-platform.whl, name-ver(-pyX.Y)?.egg
+		rest, _, _ = strings.Cut(rest[:len(rest)-len(".whl")], "-")
+	default:
+		ext := ""
+		for _, e := range []string{".tar.gz", ".tar.bz2", ".tar.xz", ".tar.z", ".tgz", ".tbz", ".zip", ".tar"} {
+			if strings.HasSuffix(lower, e) {
+				ext = e
+				break
+			}
+		}
+		if ext == "" {
+			return "", false
+		}
+		rest = rest[:len(rest)-len(ext)]
+	}
+	if rest == "" {
+		return "", false
+	}
+	return rest, true
+}
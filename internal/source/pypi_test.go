@@ -0,0 +1,192 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestPyPI(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pypi/pip-tools/json", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"info": {"name": "pip-tools"},
+			"releases": {
+				"7.0.0": [
+					{"filename": "pip_tools-7.0.0-py3-none-any.whl", "upload_time_iso_8601": "2023-07-01T10:00:00.000000Z", "requires_python": ">=3.7", "yanked": false},
+					{"filename": "pip-tools-7.0.0.tar.gz", "upload_time_iso_8601": "2023-07-01T09:00:00.000000Z", "requires_python": ">=3.7", "yanked": false}
+				],
+				"7.1.0": [
+					{"filename": "pip_tools-7.1.0.tar.gz", "upload_time_iso_8601": "2023-08-01T00:00:00Z", "requires_python": ">=3.8", "yanked": true, "yanked_reason": "broken build"}
+				],
+				"7.2.0rc1": [
+					{"filename": "pip_tools-7.2.0rc1.tar.gz", "upload_time_iso_8601": "2023-09-01T00:00:00Z", "requires_python": ">=3.8", "yanked": false}
+				],
+				"6.0.0": []
+			}
+		}`))
+	})
+	mux.HandleFunc("GET /simple/zope-interface/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.pypi.simple.v1+json")
+		_, _ = w.Write([]byte(`{
+			"meta": {"api-version": "1.1"},
+			"name": "zope-interface",
+			"files": [
+				{"filename": "zope.interface-6.0.tar.gz", "requires-python": ">=3.7", "yanked": false, "upload-time": "2023-03-01T00:00:00Z"},
+				{"filename": "zope.interface-6.0-cp311-cp311-manylinux_2_17_x86_64.whl", "yanked": false, "upload-time": "2023-03-02T00:00:00Z"},
+				{"filename": "zope.interface-6.1.tar.gz", "yanked": "security issue"},
+				{"filename": "zope.interface.extra-1.0.tar.gz"}
+			]
+		}`))
+	})
+	mux.HandleFunc("GET /html/my-pkg/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<!DOCTYPE html><html><body>
+			<a href="/files/my_pkg-1.0.0-py3-none-any.whl#sha256=abc" data-requires-python="&gt;=3.9">my_pkg-1.0.0-py3-none-any.whl</a><br/>
+			<a href='/files/My-Pkg-1.1.0.tar.gz' data-yanked>My-Pkg-1.1.0.tar.gz</a><br/>
+			<a href="/files/my-pkg-1.2.0.post1.zip" data-requires-python="&gt;=3.9,&lt;4">my-pkg-1.2.0.post1.zip</a>
+		</body></html>`))
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name    string
+		args    map[string]string
+		expErr  bool
+		expVers []string
+		expMeta map[string]PyPIRelease
+	}{
+		{
+			name: "json",
+			args: map[string]string{
+				"package": "pip-tools",
+				"index":   ts.URL + "/",
+			},
+			expVers: []string{"7.0.0", "7.2.0rc1"},
+			expMeta: map[string]PyPIRelease{
+				"7.0.0": {Version: "7.0.0", RequiresPython: ">=3.7"},
+			},
+		},
+		{
+			name: "json yanked",
+			args: map[string]string{
+				"package":     "pip-tools",
+				"index":       ts.URL,
+				"allowYanked": "true",
+			},
+			expVers: []string{"7.0.0", "7.1.0", "7.2.0rc1"},
+			expMeta: map[string]PyPIRelease{
+				"7.1.0": {Version: "7.1.0", RequiresPython: ">=3.8", Yanked: true, YankedReason: "broken build"},
+			},
+		},
+		{
+			name: "simple json",
+			args: map[string]string{
+				"package": "Zope.Interface",
+				"index":   ts.URL + "/simple",
+				"api":     "simple",
+			},
+			expVers: []string{"6.0"},
+			expMeta: map[string]PyPIRelease{
+				"6.0": {Version: "6.0", RequiresPython: ">=3.7"},
+			},
+		},
+		{
+			name: "simple html",
+			args: map[string]string{
+				"package": "my_pkg",
+				"index":   ts.URL + "/html",
+				"api":     "simple",
+			},
+			expVers: []string{"1.0.0", "1.2.0.post1"},
+			expMeta: map[string]PyPIRelease{
+				"1.2.0.post1": {Version: "1.2.0.post1", RequiresPython: ">=3.9,<4"},
+			},
+		},
+		{
+			name: "missing package",
+			args: map[string]string{
+				"package": "missing",
+				"index":   ts.URL,
+			},
+			expErr: true,
+		},
+		{
+			name: "invalid api",
+			args: map[string]string{
+				"package": "pip-tools",
+				"api":     "xmlrpc",
+			},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := newPyPI(config.Source{
+				Name: tc.name,
+				Type: "pypi",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != len(tc.expVers) {
+				t.Errorf("unexpected results, expected %v, received %v", tc.expVers, res.VerMap)
+			}
+			for _, ver := range tc.expVers {
+				if res.VerMap[ver] != ver {
+					t.Errorf("missing version %s in %v", ver, res.VerMap)
+				}
+			}
+			for ver, exp := range tc.expMeta {
+				meta, ok := res.VerMeta[ver].(*PyPIRelease)
+				if !ok {
+					t.Errorf("missing metadata for %s", ver)
+					continue
+				}
+				exp.UploadTime = meta.UploadTime
+				if *meta != exp {
+					t.Errorf("unexpected metadata for %s, expected %v, received %v", ver, exp, *meta)
+				}
+			}
+		})
+	}
+
+	t.Run("upload time", func(t *testing.T) {
+		res, err := newPyPI(config.Source{
+			Name: "upload time",
+			Type: "pypi",
+			Args: tests[0].args,
+		})
+		if err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		// the earliest upload of the release files is used
+		if meta := res.VerMeta["7.0.0"].(*PyPIRelease); meta.UploadTime.Hour() != 9 {
+			t.Errorf("unexpected upload time: %v", meta.UploadTime)
+		}
+	})
+}
@@ -16,24 +16,52 @@ package source
 
 import (
 	"context"
+	"encoding/gob"
 	"fmt"
+	"strconv"
 	"sync"
 
 	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/platform"
 	"github.com/regclient/regclient/types/ref"
 
 	"github.com/sudo-bmitch/version-bump/internal/config"
 )
 
+const (
+	regArgPlatform  = "platform"
+	regArgPlatforms = "platforms"
+)
+
 var registry struct {
-	once        sync.Once
-	rc          *regclient.RegClient
-	mu          sync.Mutex // mutex for cache access
-	cacheTags   map[string]*Results
-	cacheDigest map[string]*Results
+	once           sync.Once
+	rc             *regclient.RegClient
+	mu             sync.Mutex // mutex for cache access
+	cacheTags      map[string]*Results
+	cacheTagDigest map[string]*Results
+	cacheDigest    map[string]*Results
+}
+
+func init() {
+	gob.Register(&RegTag{})
 }
 
 func newRegistry(conf config.Source) (Results, error) {
+	regSetup()
+	switch conf.Args["type"] {
+	case "tag":
+		return regGetTag(conf)
+	case "tag-digest":
+		return regGetTagDigest(conf)
+	}
+	// default request is for a digest
+	return regGetDigest(conf)
+}
+
+// regSetup creates the regclient instance shared by sources that query registries.
+func regSetup() *regclient.RegClient {
 	registry.once.Do(func() {
 		registry.rc = regclient.New(
 			regclient.WithDockerCreds(),
@@ -41,12 +69,9 @@ func newRegistry(conf config.Source) (Results, error) {
 		)
 		registry.cacheDigest = map[string]*Results{}
 		registry.cacheTags = map[string]*Results{}
+		registry.cacheTagDigest = map[string]*Results{}
 	})
-	if conf.Args["type"] == "tag" {
-		return regGetTag(conf)
-	}
-	// default request is for a digest
-	return regGetDigest(conf)
+	return registry.rc
 }
 
 func regGetTag(conf config.Source) (Results, error) {
@@ -80,30 +105,244 @@ func regGetTag(conf config.Source) (Results, error) {
 	return res, nil
 }
 
+// regGetTagDigest lists the tags of a repo, with a [RegTag] in the VerMeta of each tag to resolve the digest.
+// The digest is only requested for the tag selected by the processor template, e.g.
+// "{{ .Version }}@{{ (index .VerMeta .Version).Digest }}".
+func regGetTagDigest(conf config.Source) (Results, error) {
+	tags, err := regGetTag(conf)
+	if err != nil {
+		return Results{}, err
+	}
+	repo := conf.Args["repo"]
+	platStr := conf.Args[regArgPlatform]
+	key := repo + ":" + platStr
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if res, ok := registry.cacheTagDigest[key]; ok {
+		return *res, nil
+	}
+	res := Results{
+		VerMap:  tags.VerMap,
+		VerMeta: map[string]any{},
+	}
+	for tag := range tags.VerMap {
+		res.VerMeta[tag] = &RegTag{
+			Repo:     repo,
+			Tag:      tag,
+			Platform: platStr,
+		}
+	}
+	registry.cacheTagDigest[key] = &res
+	return res, nil
+}
+
+// RegTag is a tag in a repository that resolves the digest when first requested.
+type RegTag struct {
+	Repo     string
+	Tag      string
+	Platform string // Platform selects the digest of a single platform from an index, when set
+	mu       sync.Mutex
+	digest   string
+}
+
+// regTagGob is the cached content of a [RegTag], including the digest once resolved.
+type regTagGob struct {
+	Repo     string
+	Tag      string
+	Platform string
+	Digest   string
+}
+
+// GobEncode includes the digest in the cache when it was resolved.
+func (t *RegTag) GobEncode() ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return gobEncode(regTagGob{
+		Repo:     t.Repo,
+		Tag:      t.Tag,
+		Platform: t.Platform,
+		Digest:   t.digest,
+	})
+}
+
+// GobDecode restores the tag from the cache.
+func (t *RegTag) GobDecode(b []byte) error {
+	c := regTagGob{}
+	if err := gobDecode(b, &c); err != nil {
+		return err
+	}
+	t.Repo, t.Tag, t.Platform, t.digest = c.Repo, c.Tag, c.Platform, c.Digest
+	return nil
+}
+
+// cacheRestore is a no-op, the registry credentials are read from the docker config.
+func (t *RegTag) cacheRestore(src config.Source) {}
+
+// Digest returns the digest of the tag.
+func (t *RegTag) Digest() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.digest != "" {
+		return t.digest, nil
+	}
+	rc := regSetup()
+	r, err := ref.New(t.Repo)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse repo: %w", err)
+	}
+	r = r.SetTag(t.Tag)
+	ctx := context.Background()
+	defer rc.Close(ctx, r)
+	if t.Platform == "" {
+		m, err := rc.ManifestHead(ctx, r, regclient.WithManifestRequireDigest())
+		if err != nil {
+			return "", fmt.Errorf("failed to query image: %w", err)
+		}
+		t.digest = m.GetDescriptor().Digest.String()
+		return t.digest, nil
+	}
+	m, err := rc.ManifestGet(ctx, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to query image: %w", err)
+	}
+	t.digest, err = regPlatformDigest(ctx, r, m, t.Platform)
+	return t.digest, err
+}
+
 func regGetDigest(conf config.Source) (Results, error) {
 	image, ok := conf.Args["image"]
 	if !ok {
 		return Results{}, fmt.Errorf("image not defined")
 	}
+	platStr := conf.Args[regArgPlatform]
+	listPlatforms := false
+	if val, ok := conf.Args[regArgPlatforms]; ok {
+		var err error
+		listPlatforms, err = strconv.ParseBool(val)
+		if err != nil {
+			return Results{}, fmt.Errorf("platforms must be a bool value: \"%s\": %w", val, err)
+		}
+	}
+	key := fmt.Sprintf("%s:%s:%t", image, platStr, listPlatforms)
 	registry.mu.Lock()
 	defer registry.mu.Unlock()
-	if res, ok := registry.cacheDigest[image]; ok {
+	if res, ok := registry.cacheDigest[key]; ok {
 		return *res, nil
 	}
 	imageRef, err := ref.New(image)
 	if err != nil {
 		return Results{}, fmt.Errorf("failed to parse image: %w", err)
 	}
-	m, err := registry.rc.ManifestHead(context.Background(), imageRef, regclient.WithManifestRequireDigest())
+	ctx := context.Background()
+	defer registry.rc.Close(ctx, imageRef)
+	if platStr == "" && !listPlatforms {
+		m, err := registry.rc.ManifestHead(ctx, imageRef, regclient.WithManifestRequireDigest())
+		if err != nil {
+			return Results{}, fmt.Errorf("failed to query image: %w", err)
+		}
+		dig := m.GetDescriptor().Digest.String()
+		res := Results{
+			VerMap: map[string]string{
+				dig: dig,
+			},
+		}
+		registry.cacheDigest[key] = &res
+		return res, nil
+	}
+	// the full manifest is needed to read the list of platforms
+	m, err := registry.rc.ManifestGet(ctx, imageRef)
 	if err != nil {
 		return Results{}, fmt.Errorf("failed to query image: %w", err)
 	}
 	dig := m.GetDescriptor().Digest.String()
+	if platStr != "" {
+		dig, err = regPlatformDigest(ctx, imageRef, m, platStr)
+		if err != nil {
+			return Results{}, err
+		}
+	}
 	res := Results{
 		VerMap: map[string]string{
 			dig: dig,
 		},
 	}
-	registry.cacheDigest[image] = &res
+	if listPlatforms {
+		platforms, err := regPlatforms(ctx, imageRef, m)
+		if err != nil {
+			return Results{}, err
+		}
+		res.VerMeta = map[string]any{
+			dig: platforms,
+		}
+	}
+	registry.cacheDigest[key] = &res
 	return res, nil
 }
+
+// regPlatformDigest returns the digest of the manifest for a platform.
+// An image without an index must match the platform from its config.
+func regPlatformDigest(ctx context.Context, r ref.Ref, m manifest.Manifest, platStr string) (string, error) {
+	var plat platform.Platform
+	var err error
+	if platStr == "local" {
+		plat = platform.Local()
+	} else {
+		plat, err = platform.Parse(platStr)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse platform %s: %w", platStr, err)
+		}
+	}
+	if mi, ok := m.(manifest.Indexer); ok {
+		dl, err := mi.GetManifestList()
+		if err != nil {
+			return "", fmt.Errorf("failed to get manifest list: %w", err)
+		}
+		// only consider the same OS and architecture, regclient would otherwise select images the host can emulate
+		candidates := []descriptor.Descriptor{}
+		for _, d := range dl {
+			if d.Platform != nil && d.Platform.OS == plat.OS && d.Platform.Architecture == plat.Architecture {
+				candidates = append(candidates, d)
+			}
+		}
+		d, err := descriptor.DescriptorListSearch(candidates, descriptor.MatchOpt{Platform: &plat})
+		if err != nil {
+			return "", fmt.Errorf("failed to find platform %s in %s: %w", platStr, r.CommonName(), err)
+		}
+		return d.Digest.String(), nil
+	}
+	conf, err := registry.rc.ImageConfig(ctx, r)
+	if err != nil {
+		return "", fmt.Errorf("failed to get image config: %w", err)
+	}
+	if imgPlat := conf.GetConfig().Platform; !platform.Match(plat, imgPlat) {
+		return "", fmt.Errorf("image %s is for platform %s, not %s", r.CommonName(), imgPlat.String(), platStr)
+	}
+	return m.GetDescriptor().Digest.String(), nil
+}
+
+// regPlatforms returns a map of each platform in an index to the digest of that platform's manifest.
+// Entries without a platform and attestations (unknown/unknown) are skipped.
+// An image without an index returns the platform from its config.
+func regPlatforms(ctx context.Context, r ref.Ref, m manifest.Manifest) (map[string]string, error) {
+	platforms := map[string]string{}
+	mi, ok := m.(manifest.Indexer)
+	if !ok {
+		conf, err := registry.rc.ImageConfig(ctx, r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get image config: %w", err)
+		}
+		platforms[conf.GetConfig().Platform.String()] = m.GetDescriptor().Digest.String()
+		return platforms, nil
+	}
+	dl, err := mi.GetManifestList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest list: %w", err)
+	}
+	for _, d := range dl {
+		if d.Platform == nil || d.Platform.OS == "unknown" {
+			continue
+		}
+		platforms[d.Platform.String()] = d.Digest.String()
+	}
+	return platforms, nil
+}
@@ -0,0 +1,335 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/regclient/regclient/types/descriptor"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/mediatype"
+	"github.com/regclient/regclient/types/oci"
+	v1 "github.com/regclient/regclient/types/oci/v1"
+	"github.com/regclient/regclient/types/platform"
+	"github.com/regclient/regclient/types/ref"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+	"github.com/sudo-bmitch/version-bump/internal/template"
+)
+
+// regTestLayout creates an OCI Layout with a multi-platform image at multi:v1 and a single platform image at single:v1.
+// The returned map contains the digest of each image and platform.
+func regTestLayout(t *testing.T) (string, map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	ctx := context.Background()
+	rc := regSetup()
+	digests := map[string]string{}
+	putImage := func(r ref.Ref, plat string) descriptor.Descriptor {
+		p, err := platform.Parse(plat)
+		if err != nil {
+			t.Fatalf("failed to parse platform: %v", err)
+		}
+		cd, err := rc.BlobPut(ctx, r, descriptor.Descriptor{MediaType: mediatype.OCI1ImageConfig},
+			bytes.NewReader([]byte(`{"architecture":"`+p.Architecture+`","os":"`+p.OS+`","rootfs":{"type":"layers","diff_ids":[]}}`)))
+		if err != nil {
+			t.Fatalf("failed to push config: %v", err)
+		}
+		m, err := manifest.New(manifest.WithOrig(v1.Manifest{
+			Versioned: oci.Versioned{SchemaVersion: 2},
+			MediaType: mediatype.OCI1Manifest,
+			Config:    cd,
+			Layers:    []descriptor.Descriptor{},
+		}))
+		if err != nil {
+			t.Fatalf("failed to create manifest: %v", err)
+		}
+		if err := rc.ManifestPut(ctx, r.SetDigest(m.GetDescriptor().Digest.String()), m); err != nil {
+			t.Fatalf("failed to push manifest: %v", err)
+		}
+		d := m.GetDescriptor()
+		d.Platform = &p
+		return d
+	}
+	multi, err := ref.New("ocidir://" + dir + "/multi:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	dl := []descriptor.Descriptor{}
+	for _, plat := range []string{"linux/amd64", "linux/arm64", "unknown/unknown"} {
+		d := putImage(multi, plat)
+		digests[plat] = d.Digest.String()
+		dl = append(dl, d)
+	}
+	mi, err := manifest.New(manifest.WithOrig(v1.Index{
+		Versioned: oci.Versioned{SchemaVersion: 2},
+		MediaType: mediatype.OCI1ManifestList,
+		Manifests: dl,
+	}))
+	if err != nil {
+		t.Fatalf("failed to create index: %v", err)
+	}
+	if err := rc.ManifestPut(ctx, multi, mi); err != nil {
+		t.Fatalf("failed to push index: %v", err)
+	}
+	digests["multi"] = mi.GetDescriptor().Digest.String()
+	single, err := ref.New("ocidir://" + dir + "/single:v1")
+	if err != nil {
+		t.Fatalf("failed to parse ref: %v", err)
+	}
+	d := putImage(single, "linux/s390x")
+	if err := rc.ManifestPut(ctx, single, mustManifestGet(t, single.SetDigest(d.Digest.String()))); err != nil {
+		t.Fatalf("failed to tag single image: %v", err)
+	}
+	digests["single"] = d.Digest.String()
+	_ = rc.Close(ctx, multi)
+	_ = rc.Close(ctx, single)
+	return dir, digests
+}
+
+func mustManifestGet(t *testing.T, r ref.Ref) manifest.Manifest {
+	t.Helper()
+	m, err := regSetup().ManifestGet(context.Background(), r)
+	if err != nil {
+		t.Fatalf("failed to get manifest: %v", err)
+	}
+	return m
+}
+
+func TestRegistryDigest(t *testing.T) {
+	dir, digests := regTestLayout(t)
+
+	tests := []struct {
+		name         string
+		args         map[string]string
+		expErr       bool
+		expDigest    string
+		expPlatforms map[string]string
+	}{
+		{
+			name:      "index",
+			args:      map[string]string{"image": "ocidir://" + dir + "/multi:v1"},
+			expDigest: digests["multi"],
+		},
+		{
+			name: "platform",
+			args: map[string]string{
+				"image":    "ocidir://" + dir + "/multi:v1",
+				"platform": "linux/arm64",
+			},
+			expDigest: digests["linux/arm64"],
+		},
+		{
+			name: "platforms",
+			args: map[string]string{
+				"image":     "ocidir://" + dir + "/multi:v1",
+				"platforms": "true",
+			},
+			expDigest: digests["multi"],
+			expPlatforms: map[string]string{
+				"linux/amd64": digests["linux/amd64"],
+				"linux/arm64": digests["linux/arm64"],
+			},
+		},
+		{
+			name: "platform and platforms",
+			args: map[string]string{
+				"image":     "ocidir://" + dir + "/multi:v1",
+				"platform":  "linux/amd64",
+				"platforms": "true",
+			},
+			expDigest: digests["linux/amd64"],
+			expPlatforms: map[string]string{
+				"linux/amd64": digests["linux/amd64"],
+				"linux/arm64": digests["linux/arm64"],
+			},
+		},
+		{
+			name: "missing platform",
+			args: map[string]string{
+				"image":    "ocidir://" + dir + "/multi:v1",
+				"platform": "windows/amd64",
+			},
+			expErr: true,
+		},
+		{
+			name: "single image",
+			args: map[string]string{
+				"image":     "ocidir://" + dir + "/single:v1",
+				"platform":  "linux/s390x",
+				"platforms": "true",
+			},
+			expDigest: digests["single"],
+			expPlatforms: map[string]string{
+				"linux/s390x": digests["single"],
+			},
+		},
+		{
+			name: "single image wrong platform",
+			args: map[string]string{
+				"image":    "ocidir://" + dir + "/single:v1",
+				"platform": "linux/amd64",
+			},
+			expErr: true,
+		},
+		{
+			name: "invalid platforms",
+			args: map[string]string{
+				"image":     "ocidir://" + dir + "/multi:v1",
+				"platforms": "all",
+			},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := newRegistry(config.Source{
+				Name: tc.name,
+				Type: "registry",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != 1 || res.VerMap[tc.expDigest] != tc.expDigest {
+				t.Errorf("unexpected digest, expected %s, received %v", tc.expDigest, res.VerMap)
+			}
+			if tc.expPlatforms == nil {
+				if res.VerMeta != nil {
+					t.Errorf("unexpected metadata: %v", res.VerMeta)
+				}
+				return
+			}
+			platforms, ok := res.VerMeta[tc.expDigest].(map[string]string)
+			if !ok || len(platforms) != len(tc.expPlatforms) {
+				t.Fatalf("unexpected platforms, expected %v, received %v", tc.expPlatforms, res.VerMeta[tc.expDigest])
+			}
+			for p, d := range tc.expPlatforms {
+				if platforms[p] != d {
+					t.Errorf("unexpected digest for %s, expected %s, received %s", p, d, platforms[p])
+				}
+			}
+		})
+	}
+}
+
+func TestRegistryTagDigest(t *testing.T) {
+	dir, digests := regTestLayout(t)
+	tmpl := "{{ .Version }}@{{ (index .VerMeta .Version).Digest }}"
+
+	tests := []struct {
+		name   string
+		args   map[string]string
+		expErr bool
+		expOut string
+	}{
+		{
+			name: "index",
+			args: map[string]string{
+				"repo": "ocidir://" + dir + "/multi",
+				"type": "tag-digest",
+			},
+			expOut: "v1@" + digests["multi"],
+		},
+		{
+			name: "platform",
+			args: map[string]string{
+				"repo":     "ocidir://" + dir + "/multi",
+				"type":     "tag-digest",
+				"platform": "linux/arm64",
+			},
+			expOut: "v1@" + digests["linux/arm64"],
+		},
+		{
+			name: "missing platform",
+			args: map[string]string{
+				"repo":     "ocidir://" + dir + "/single",
+				"type":     "tag-digest",
+				"platform": "linux/arm64",
+			},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := newRegistry(config.Source{
+				Name: tc.name,
+				Type: "registry",
+				Args: tc.args,
+			})
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != 1 || res.VerMap["v1"] != "v1" {
+				t.Fatalf("unexpected tags: %v", res.VerMap)
+			}
+			// the digest is resolved by the processor template
+			out, err := template.String(tmpl, struct {
+				Results
+				Version string
+			}{Results: res, Version: "v1"})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail, output %s", out)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to template: %v", err)
+			}
+			if out != tc.expOut {
+				t.Errorf("unexpected output, expected %s, received %s", tc.expOut, out)
+			}
+		})
+	}
+
+	t.Run("cache", func(t *testing.T) {
+		CacheSetup(CacheOpts{Dir: t.TempDir(), TTL: time.Hour})
+		t.Cleanup(func() { CacheSetup(CacheOpts{}) })
+		src := config.Source{
+			Name: "cache",
+			Type: "registry",
+			Args: tests[1].args,
+		}
+		res, err := newRegistry(src)
+		if err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		if err := cachePut(src, res); err != nil {
+			t.Fatalf("failed to cache results: %v", err)
+		}
+		cached, ok := cacheGet(src, time.Hour)
+		if !ok {
+			t.Fatalf("cached results not found")
+		}
+		rt, ok := cached.VerMeta["v1"].(*RegTag)
+		if !ok {
+			t.Fatalf("unexpected cached metadata: %v", cached.VerMeta["v1"])
+		}
+		dig, err := rt.Digest()
+		if err != nil || dig != digests["linux/arm64"] {
+			t.Errorf("unexpected digest from cache, expected %s, received %s, err %v", digests["linux/arm64"], dig, err)
+		}
+	})
+}
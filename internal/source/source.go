@@ -22,12 +22,19 @@ import (
 )
 
 var sourceTypes map[string]func(config.Source) (Results, error) = map[string]func(config.Source) (Results, error){
-	"custom":     newCustom,
-	"git":        newGit,
-	"manual":     newManual,
-	"registry":   newRegistry,
-	"gh-release": newGHRelease,
-	// TODO: add url (headers, parse json/yaml, parse regex), github release
+	"custom":         newCustom,
+	"file":           newFile,
+	"git":            newGit,
+	"git-local":      newGitLocal,
+	"manual":         newManual,
+	"npm":            newNPM,
+	"pypi":           newPyPI,
+	"registry":       newRegistry,
+	"gh-release":     newGHRelease,
+	"gitlab-release": newGLRelease,
+	"gomod":          newGoMod,
+	"helm":           newHelm,
+	"url":            newURL,
 }
 
 // Results are returned by a source for a given request.
@@ -36,9 +43,25 @@ type Results struct {
 	VerMeta map[string]any    // additional metadata specific to each source, e.g. GitHub release metadata
 }
 
+// Get returns the results from a source, using the persistent cache when configured.
 func Get(src config.Source) (Results, error) {
-	if srcFn, ok := sourceTypes[src.Type]; ok {
-		return srcFn(src)
+	srcFn, ok := sourceTypes[src.Type]
+	if !ok {
+		return Results{}, fmt.Errorf("source type not found: %s", src.Type)
 	}
-	return Results{}, fmt.Errorf("source type not found: %s", src.Type)
+	ttl, err := cacheTTL(src)
+	if err != nil {
+		return Results{}, err
+	}
+	if ttl > 0 {
+		if res, ok := cacheGet(src, ttl); ok {
+			return res, nil
+		}
+	}
+	res, err := srcFn(src)
+	if err == nil && ttl > 0 {
+		// failing to save the cache does not prevent the results from being used
+		_ = cachePut(src, res)
+	}
+	return res, err
 }
@@ -0,0 +1,341 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+	"github.com/sudo-bmitch/version-bump/internal/datapath"
+)
+
+const (
+	urlArgURL          = "url"
+	urlArgMethod       = "method"
+	urlArgBody         = "body"
+	urlArgHeaderPrefix = "header."
+	urlArgType         = "type"
+	urlArgPath         = "path"
+	urlArgRegexp       = "regexp"
+	urlTypeJSON        = "json"
+	urlTypeYAML        = "yaml"
+	urlTypeRegexp      = "regexp"
+	urlVersion         = "Version"
+)
+
+var urlState struct {
+	once       sync.Once
+	httpClient *http.Client
+	mu         sync.Mutex // mutex for cache access
+	cache      map[string]*Results
+}
+
+// newURL fetches a URL and extracts the versions from the response.
+// The type may be json or yaml with a path to the versions, or regexp with a Version submatch.
+// With json and yaml, the optional regexp extracts the Version from each value.
+func newURL(conf config.Source) (Results, error) {
+	u, ok := conf.Args[urlArgURL]
+	if !ok {
+		return Results{}, fmt.Errorf("url argument is required")
+	}
+	urlState.once.Do(func() {
+		urlState.httpClient = http.DefaultClient
+		urlState.cache = map[string]*Results{}
+	})
+	var err error
+	typ := conf.Args[urlArgType]
+	if typ == "" {
+		typ = urlTypeJSON
+	}
+	var path *datapath.Path
+	switch typ {
+	case urlTypeJSON, urlTypeYAML:
+		if _, ok := conf.Args[urlArgPath]; !ok {
+			return Results{}, fmt.Errorf("path argument is required for type %s", typ)
+		}
+		path, err = datapath.Parse(conf.Args[urlArgPath])
+		if err != nil {
+			return Results{}, err
+		}
+	case urlTypeRegexp:
+		if _, ok := conf.Args[urlArgRegexp]; !ok {
+			return Results{}, fmt.Errorf("regexp argument is required for type %s", typ)
+		}
+	default:
+		return Results{}, fmt.Errorf("unsupported type: %s", typ)
+	}
+	var re *regexp.Regexp
+	if expr, ok := conf.Args[urlArgRegexp]; ok {
+		re, err = regexp.Compile(expr)
+		if err != nil {
+			return Results{}, fmt.Errorf("failed to compile regexp %s: %w", expr, err)
+		}
+		if re.SubexpIndex(urlVersion) < 0 {
+			return Results{}, fmt.Errorf("regexp is missing a Version submatch (i.e. \"(?P<Version>\\d+)\"): %s", expr)
+		}
+	}
+	// the cache key includes all args since each changes the results
+	key, err := json.Marshal(conf.Args)
+	if err != nil {
+		return Results{}, err
+	}
+	urlState.mu.Lock()
+	defer urlState.mu.Unlock()
+	if res, ok := urlState.cache[string(key)]; ok {
+		return *res, nil
+	}
+	body, err := urlFetch(conf, u)
+	if err != nil {
+		return Results{}, err
+	}
+	res, err := urlParse(body, typ, path, re)
+	if err != nil {
+		return Results{}, fmt.Errorf("failed to parse %s response from %s: %w", typ, u, err)
+	}
+	if len(res.VerMap) == 0 {
+		return Results{}, fmt.Errorf("no versions found in %s", u)
+	}
+	urlState.cache[string(key)] = &res
+	return res, nil
+}
+
+// urlParse extracts the versions from a json or yaml document with the path, or from the content with the regexp.
+// The regexp is optional for json and yaml, and extracts the Version submatch from each value.
+func urlParse(body []byte, typ string, path *datapath.Path, re *regexp.Regexp) (Results, error) {
+	var err error
+	res := Results{
+		VerMap:  map[string]string{},
+		VerMeta: map[string]any{},
+	}
+	switch typ {
+	case urlTypeJSON, urlTypeYAML:
+		// numbers are preserved as a json.Number with the original text, e.g. 1.10 is not converted to 1.1
+		var doc any
+		if typ == urlTypeJSON {
+			dec := json.NewDecoder(bytes.NewReader(body))
+			dec.UseNumber()
+			err = dec.Decode(&doc)
+		} else {
+			doc, err = urlYAML(body)
+		}
+		if err != nil {
+			return Results{}, err
+		}
+		for _, found := range path.Find(doc) {
+			ver, ok := urlScalar(found.Value)
+			if !ok {
+				continue
+			}
+			// metadata is the containing object, e.g. the release entry with the version
+			meta := found.Value
+			if _, ok := found.Parent.(map[string]any); ok {
+				meta = found.Parent
+			}
+			if re != nil {
+				match := re.FindStringSubmatch(ver)
+				if match == nil {
+					continue
+				}
+				ver = match[re.SubexpIndex(urlVersion)]
+			}
+			if ver == "" {
+				continue
+			}
+			res.VerMap[ver] = ver
+			res.VerMeta[ver] = meta
+		}
+	case urlTypeRegexp:
+		for _, match := range re.FindAllSubmatch(body, -1) {
+			meta := map[string]string{}
+			for i, name := range re.SubexpNames() {
+				if name != "" {
+					meta[name] = string(match[i])
+				}
+			}
+			ver := meta[urlVersion]
+			if ver == "" {
+				continue
+			}
+			res.VerMap[ver] = ver
+			res.VerMeta[ver] = meta
+		}
+	}
+	return res, nil
+}
+
+// urlFetch sends the request and returns the response body.
+func urlFetch(conf config.Source, u string) ([]byte, error) {
+	method := conf.Args[urlArgMethod]
+	if method == "" {
+		method = http.MethodGet
+	}
+	var reqBody io.Reader
+	if body, ok := conf.Args[urlArgBody]; ok {
+		reqBody = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(strings.ToUpper(method), u, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url %s: %w", u, err)
+	}
+	auth, err := newHTTPAuth(conf, parsed)
+	if err != nil {
+		return nil, err
+	}
+	auth.bearer = true
+	auth.set(req)
+	// headers from the args override any auth
+	for k, v := range conf.Args {
+		if name, ok := strings.CutPrefix(k, urlArgHeaderPrefix); ok && name != "" {
+			req.Header.Set(name, v)
+		}
+	}
+	//#nosec G704 config file containing URL is controlled by user running the command
+	resp, err := urlState.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response from %s: %w", u, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status from %s, status: %d, body: %s", u, resp.StatusCode, string(b))
+	}
+	return b, nil
+}
+
+// urlYAML decodes the first document of a yaml file into maps and slices, matching the output of encoding/json.
+// The AST is used instead of yaml.Unmarshal to keep the original text of numbers.
+func urlYAML(body []byte) (any, error) {
+	f, err := parser.ParseBytes(body, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Docs) == 0 {
+		return nil, nil
+	}
+	return urlYAMLNode(f.Docs[0].Body, map[string]any{}), nil
+}
+
+// urlYAMLNode converts a node, tracking anchors to resolve aliases.
+func urlYAMLNode(node ast.Node, anchors map[string]any) any {
+	switch n := node.(type) {
+	case nil, *ast.NullNode:
+		return nil
+	case *ast.DocumentNode:
+		return urlYAMLNode(n.Body, anchors)
+	case *ast.AnchorNode:
+		v := urlYAMLNode(n.Value, anchors)
+		anchors[n.Name.GetToken().Value] = v
+		return v
+	case *ast.AliasNode:
+		return anchors[n.Value.GetToken().Value]
+	case *ast.TagNode:
+		// explicit strings, e.g. !!str 1.20, use the original text
+		if n.Start != nil && n.Start.Value == "!!str" {
+			if scalar, ok := n.Value.(ast.ScalarNode); ok {
+				return scalar.GetToken().Value
+			}
+		}
+		return urlYAMLNode(n.Value, anchors)
+	case *ast.MappingKeyNode:
+		return urlYAMLNode(n.Value, anchors)
+	case *ast.MappingNode:
+		return urlYAMLMapping(n.Values, anchors)
+	case *ast.MappingValueNode:
+		return urlYAMLMapping([]*ast.MappingValueNode{n}, anchors)
+	case *ast.SequenceNode:
+		list := make([]any, 0, len(n.Values))
+		for _, v := range n.Values {
+			list = append(list, urlYAMLNode(v, anchors))
+		}
+		return list
+	case *ast.LiteralNode:
+		return n.Value.Value
+	case *ast.StringNode:
+		return n.Value
+	case *ast.BoolNode:
+		return n.Value
+	case *ast.IntegerNode, *ast.FloatNode:
+		return json.Number(n.GetToken().Value)
+	case ast.ScalarNode:
+		return n.GetToken().Value
+	}
+	return nil
+}
+
+// urlYAMLMapping converts the values of a mapping, where explicit keys override keys from a merge ("<<").
+func urlYAMLMapping(values []*ast.MappingValueNode, anchors map[string]any) map[string]any {
+	m := map[string]any{}
+	explicit := map[string]any{}
+	for _, mv := range values {
+		if mv.Key == nil {
+			continue
+		}
+		v := urlYAMLNode(mv.Value, anchors)
+		if mv.Key.IsMergeKey() {
+			merge, ok := v.([]any)
+			if !ok {
+				merge = []any{v}
+			}
+			for _, item := range merge {
+				if mm, ok := item.(map[string]any); ok {
+					maps.Copy(m, mm)
+				}
+			}
+			continue
+		}
+		if k, ok := urlScalar(urlYAMLNode(mv.Key, anchors)); ok {
+			explicit[k] = v
+		}
+	}
+	maps.Copy(m, explicit)
+	return m
+}
+
+// urlScalar converts a scalar value to a string.
+func urlScalar(v any) (string, bool) {
+	switch vt := v.(type) {
+	case string:
+		return vt, true
+	case json.Number:
+		return vt.String(), true
+	case bool:
+		return strconv.FormatBool(vt), true
+	case float64:
+		return strconv.FormatFloat(vt, 'f', -1, 64), true
+	case int, int64, uint64:
+		return fmt.Sprint(vt), true
+	}
+	return "", false
+}
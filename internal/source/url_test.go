@@ -0,0 +1,245 @@
+// Copyright the version-bump contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package source
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sudo-bmitch/version-bump/internal/config"
+)
+
+func TestURL(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /latest.json", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "yes" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"releases": [
+			{"version": "1.10", "url": "https://example.com/1.10"},
+			{"version": 1.9, "url": "https://example.com/1.9"},
+			{"version": "v2.0.0-rc1", "url": "https://example.com/2.0.0-rc1"}
+		]}`))
+	})
+	mux.HandleFunc("GET /index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("versions:\n  - name: '1.2.3'\n    stable: true\n  - name: '1.3.0'\n    stable: false\n"))
+	})
+	mux.HandleFunc("GET /numbers.yaml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`base: &base
+  url: https://example.com/base
+versions:
+  - name: 1.20
+    <<: *base
+  - name: 1.9
+    <<: *base
+    url: https://example.com/1.9
+  - name: !!str 2.10
+  - name: 3
+`))
+	})
+	mux.HandleFunc("GET /download.html", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<a href="/app-1.2.3.tar.gz">1.2.3</a><a href="/app-1.4.0.tar.gz">1.4.0</a>`))
+	})
+	mux.HandleFunc("POST /query", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if string(b) != `{"query":"latest"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data": {"latest": "3.0.0"}}`))
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name    string
+		args    map[string]string
+		expErr  bool
+		expVers []string
+		expMeta map[string]string // expected value of the url field in the metadata for each version
+	}{
+		{
+			name: "json",
+			args: map[string]string{
+				"url":           ts.URL + "/latest.json",
+				"header.X-Test": "yes",
+				"path":          "$.releases[*].version",
+			},
+			expVers: []string{"1.10", "1.9", "v2.0.0-rc1"},
+			expMeta: map[string]string{
+				"1.10": "https://example.com/1.10",
+				"1.9":  "https://example.com/1.9",
+			},
+		},
+		{
+			name: "json regexp",
+			args: map[string]string{
+				"url":           ts.URL + "/latest.json",
+				"header.X-Test": "yes",
+				"type":          "json",
+				"path":          "$.releases[*].version",
+				"regexp":        `^v(?P<Version>\d+\.\d+\.\d+)`,
+			},
+			expVers: []string{"2.0.0"},
+			expMeta: map[string]string{
+				"2.0.0": "https://example.com/2.0.0-rc1",
+			},
+		},
+		{
+			name: "missing header",
+			args: map[string]string{
+				"url":  ts.URL + "/latest.json",
+				"path": "$.releases[*].version",
+			},
+			expErr: true,
+		},
+		{
+			name: "yaml",
+			args: map[string]string{
+				"url":  ts.URL + "/index.yaml",
+				"type": "yaml",
+				"path": "versions[*].name",
+			},
+			expVers: []string{"1.2.3", "1.3.0"},
+		},
+		{
+			name: "yaml numbers",
+			args: map[string]string{
+				"url":  ts.URL + "/numbers.yaml",
+				"type": "yaml",
+				"path": "versions[*].name",
+			},
+			expVers: []string{"1.20", "1.9", "2.10", "3"},
+			expMeta: map[string]string{
+				"1.20": "https://example.com/base",
+				"1.9":  "https://example.com/1.9",
+			},
+		},
+		{
+			name: "regexp",
+			args: map[string]string{
+				"url":    ts.URL + "/download.html",
+				"type":   "regexp",
+				"regexp": `app-(?P<Version>\d+\.\d+\.\d+)\.tar\.gz`,
+			},
+			expVers: []string{"1.2.3", "1.4.0"},
+		},
+		{
+			name: "post",
+			args: map[string]string{
+				"url":                 ts.URL + "/query",
+				"method":              "post",
+				"body":                `{"query":"latest"}`,
+				"header.Content-Type": "application/json",
+				"path":                "/data/latest",
+			},
+			expVers: []string{"3.0.0"},
+		},
+		{
+			name: "no matches",
+			args: map[string]string{
+				"url":  ts.URL + "/index.yaml",
+				"type": "yaml",
+				"path": "$.missing",
+			},
+			expErr: true,
+		},
+		{
+			name: "missing path",
+			args: map[string]string{
+				"url": ts.URL + "/latest.json",
+			},
+			expErr: true,
+		},
+		{
+			name: "missing version submatch",
+			args: map[string]string{
+				"url":    ts.URL + "/download.html",
+				"type":   "regexp",
+				"regexp": `app-(\d+)`,
+			},
+			expErr: true,
+		},
+		{
+			name: "unknown type",
+			args: map[string]string{
+				"url":  ts.URL + "/latest.json",
+				"type": "xml",
+			},
+			expErr: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := newURL(config.Source{
+				Name: tc.name,
+				Type: "url",
+				Args: tc.args,
+			})
+			if tc.expErr {
+				if err == nil {
+					t.Errorf("did not fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed: %v", err)
+			}
+			if len(res.VerMap) != len(tc.expVers) {
+				t.Errorf("unexpected results, expected %v, received %v", tc.expVers, res.VerMap)
+			}
+			for _, ver := range tc.expVers {
+				if res.VerMap[ver] != ver {
+					t.Errorf("missing version %s in %v", ver, res.VerMap)
+				}
+			}
+			for ver, expURL := range tc.expMeta {
+				meta, ok := res.VerMeta[ver].(map[string]any)
+				if !ok || meta["url"] != expURL {
+					t.Errorf("unexpected metadata for %s: %v", ver, res.VerMeta[ver])
+				}
+			}
+		})
+	}
+
+	t.Run("cache", func(t *testing.T) {
+		CacheSetup(CacheOpts{Dir: t.TempDir(), TTL: time.Hour})
+		t.Cleanup(func() { CacheSetup(CacheOpts{}) })
+		src := config.Source{
+			Name: "cache",
+			Type: "url",
+			Args: tests[0].args,
+		}
+		res, err := newURL(src)
+		if err != nil {
+			t.Fatalf("failed: %v", err)
+		}
+		if err := cachePut(src, res); err != nil {
+			t.Fatalf("failed to cache results: %v", err)
+		}
+		cached, ok := cacheGet(src, time.Hour)
+		if !ok {
+			t.Fatalf("cached results not found")
+		}
+		meta, ok := cached.VerMeta["1.10"].(map[string]any)
+		if !ok || meta["url"] != "https://example.com/1.10" {
+			t.Errorf("unexpected cached metadata: %v", cached.VerMeta["1.10"])
+		}
+	})
+}
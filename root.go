@@ -16,7 +16,9 @@ package main
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -25,33 +27,51 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/goccy/go-yaml"
 	"github.com/spf13/cobra"
 
 	"github.com/sudo-bmitch/version-bump/internal/config"
+	"github.com/sudo-bmitch/version-bump/internal/diff"
 	"github.com/sudo-bmitch/version-bump/internal/filesearch"
 	"github.com/sudo-bmitch/version-bump/internal/lockfile"
 	"github.com/sudo-bmitch/version-bump/internal/processor"
+	"github.com/sudo-bmitch/version-bump/internal/source"
 	"github.com/sudo-bmitch/version-bump/internal/template"
 	"github.com/sudo-bmitch/version-bump/internal/version"
 )
 
 const (
-	defaultConf = ".version-bump.yml"
-	defaultLock = ".version-bump.lock"
-	envConf     = "VERSION_BUMP_CONF"
-	envLock     = "VERSION_BUMP_LOCK"
+	defaultCacheTTL = time.Hour
+	defaultConf     = ".version-bump.yml"
+	defaultLock     = ".version-bump.lock"
+	defaultFormat   = "{{printPretty .}}"
+	envCache        = "VERSION_BUMP_CACHE"
+	envConf         = "VERSION_BUMP_CONF"
+	envLock         = "VERSION_BUMP_LOCK"
+	formatJSON      = "json"
+	formatYAML      = "yaml"
 )
 
 type cliOpts struct {
+	cacheDir   string
+	cacheTTL   time.Duration
 	chdir      string
 	confFile   string
 	lockFile   string
+	diff       bool
 	dryrun     bool
+	key        string
+	locked     bool
 	prune      bool
 	format     string
 	processors []string
+	refresh    bool
 	scans      []string
+	workDir    string // workDir is the directory the command was run from, before changing to chdir
 	// TODO: setup logging
 	// verbosity string
 	// logopts   []string
@@ -90,9 +110,46 @@ By default, the current directory is changed to the location of the config file.
 		RunE: rootOpts.runAction,
 	}
 
-	// TODO:
+	// apply
+	applyCmd := &cobra.Command{
+		Use:     "apply <file list>",
+		Aliases: []string{"sync"},
+		Short:   "Apply versions from the lock file to files",
+		Long: `Scan each file identified in the configuration for versions.
+Update versions to match the lock file, only querying upstream sources for entries missing from the lock file.
+Update the lock file with any new entries, and report changes.
+Files or directories to scan should be passed as arguments, with the current dir as the default.
+By default, the current directory is changed to the location of the config file.`,
+		RunE: rootOpts.runAction,
+	}
+
 	// set
+	setCmd := &cobra.Command{
+		Use:   "set <version> <file list>",
+		Short: "Set a version in files and the lock file",
+		Long: `Set the version for a single processor and key in the lock file.
+Scan each file identified in the configuration, updating matches for the processor and key to the version.
+Upstream sources are not queried, and the lock is not saved when the key is not found in any file.
+Files or directories to scan may be passed as arguments after the version, with the current dir as the default.
+By default, the current directory is changed to the location of the config file.`,
+		Example: `
+# pin the alpine version
+version-bump set --processor docker-arg-alpine-tag --key docker.io/library/alpine 3.20.1`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: rootOpts.runAction,
+	}
+
 	// reset
+	resetCmd := &cobra.Command{
+		Use:   "reset <file list>",
+		Short: "Reset versions in files to the lock file",
+		Long: `Scan each file identified in the configuration for versions.
+Update versions to match the lock file without querying upstream sources.
+Versions missing from the lock file are not changed, and the lock file is not modified.
+Files or directories to scan should be passed as arguments, with the current dir as the default.
+By default, the current directory is changed to the location of the config file.`,
+		RunE: rootOpts.runAction,
+	}
 
 	// scan
 	scanCmd := &cobra.Command{
@@ -105,6 +162,20 @@ By default, the current directory is changed to the location of the config file.
 		RunE: rootOpts.runAction,
 	}
 
+	// cache
+	cacheCmd := &cobra.Command{
+		Use:   "cache <cmd>",
+		Short: "Manage the cache of source results",
+	}
+	cacheClearCmd := &cobra.Command{
+		Use:   "clear",
+		Short: "Delete cached source results",
+		Long: `Delete all source results from the cache directory.
+The cache directory is set with the --cache-dir flag or $` + envCache + `.`,
+		Args: cobra.ExactArgs(0),
+		RunE: rootOpts.runCacheClear,
+	}
+
 	versionCmd := &cobra.Command{
 		Use:   "version",
 		Short: "Show the version",
@@ -113,18 +184,37 @@ By default, the current directory is changed to the location of the config file.
 		RunE:  rootOpts.runVersion,
 	}
 
-	for _, cmd := range []*cobra.Command{checkCmd, scanCmd, updateCmd} {
+	for _, cmd := range []*cobra.Command{applyCmd, checkCmd, resetCmd, scanCmd, setCmd, updateCmd} {
 		cmd.Flags().StringVar(&rootOpts.chdir, "chdir", "", "Changes to requested directory, defaults to config file location")
 		cmd.Flags().StringVarP(&rootOpts.confFile, "conf", "c", "", "Config file to load")
 		cmd.Flags().BoolVar(&rootOpts.dryrun, "dry-run", false, "Dry run")
+		cmd.Flags().StringVar(&rootOpts.format, "format", defaultFormat, "Format output with go template syntax, or use json or yaml")
 		cmd.Flags().BoolVar(&rootOpts.prune, "prune", false, "Prune unused entries (default to true when no files are listed)")
 		cmd.Flags().StringArrayVar(&rootOpts.processors, "processor", []string{}, "Only run specific processors")
 		cmd.Flags().StringArrayVar(&rootOpts.scans, "scan", []string{}, "Deprecated: Only run specific scans")
 		_ = cmd.Flags().MarkHidden("scan")
 		rootCmd.AddCommand(cmd)
 	}
+	for _, cmd := range []*cobra.Command{checkCmd, updateCmd} {
+		cmd.Flags().BoolVar(&rootOpts.locked, "locked", false, "Use versions from the lock file, only querying sources for missing entries")
+	}
+	for _, cmd := range []*cobra.Command{applyCmd, checkCmd, updateCmd} {
+		cmd.Flags().StringVar(&rootOpts.cacheDir, "cache-dir", "", "Directory to cache source results, defaults to $"+envCache+", the cache is disabled when unset")
+		cmd.Flags().DurationVar(&rootOpts.cacheTTL, "cache-ttl", defaultCacheTTL, "Time to keep cached source results, sources may override this with cacheTTL")
+		cmd.Flags().BoolVar(&rootOpts.refresh, "refresh", false, "Query sources instead of using cached results")
+	}
+	for _, cmd := range []*cobra.Command{applyCmd, resetCmd, setCmd, updateCmd} {
+		cmd.Flags().BoolVar(&rootOpts.diff, "diff", false, "Output a unified diff of file changes, other output is written to stderr")
+	}
+	setCmd.Flags().StringVar(&rootOpts.key, "key", "", "Key of the processor to set")
+	_ = setCmd.MarkFlagRequired("key")
+	_ = setCmd.MarkFlagRequired("processor")
+
+	cacheClearCmd.Flags().StringVar(&rootOpts.cacheDir, "cache-dir", "", "Directory of cached source results, defaults to $"+envCache)
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
 
-	versionCmd.Flags().StringVar(&rootOpts.format, "format", "{{printPretty .}}", "Format output with go template syntax")
+	versionCmd.Flags().StringVar(&rootOpts.format, "format", defaultFormat, "Format output with go template syntax")
 	rootCmd.AddCommand(versionCmd)
 
 	return rootCmd
@@ -152,55 +242,135 @@ func (cli *cliOpts) runAction(cmd *cobra.Command, args []string) error {
 		cli.prune = true
 	}
 
+	action := cmd.Name()
+	warnMu := sync.Mutex{}
+	procOpts := []processor.Opt{
+		processor.WithWarnFunc(func(msg string) {
+			warnMu.Lock()
+			defer warnMu.Unlock()
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s\n", msg)
+		}),
+	}
+	setMatched := atomic.Bool{} // tracks if the key from the set command was found in any file
+	switch cmd.Name() {
+	case "apply":
+		procOpts = append(procOpts, processor.WithMode(processor.ModeLock))
+	case "check", "update":
+		if cli.locked {
+			procOpts = append(procOpts, processor.WithMode(processor.ModeLock))
+		}
+	case "reset":
+		procOpts = append(procOpts, processor.WithMode(processor.ModeReset))
+	case "scan":
+		procOpts = append(procOpts, processor.WithMode(processor.ModeScan))
+	case "set":
+		if len(cli.processors) != 1 {
+			return fmt.Errorf("set requires a single processor")
+		}
+		if _, ok := conf.Processors[cli.processors[0]]; !ok {
+			return fmt.Errorf("processor not defined: %s", cli.processors[0])
+		}
+		// the lock is updated first, and then applied to the matching entries
+		err = locks.Set(cli.processors[0], cli.key, args[0])
+		if err != nil {
+			return err
+		}
+		args = args[1:]
+		// only the one processor is run, so other lock entries are preserved
+		cli.prune = false
+		procOpts = append(procOpts, processor.WithMode(processor.ModeReset), processor.WithKey(cli.key),
+			processor.WithMatchFunc(func(string) { setMatched.Store(true) }))
+	default:
+		return fmt.Errorf("unhandled command %s", cmd.Name())
+	}
+
+	err = cli.cacheSetup()
+	if err != nil {
+		return err
+	}
+
 	// cd to appropriate location
 	if !flagChanged(cmd, "chdir") {
 		cli.chdir = filepath.Dir(cli.confFile)
 	}
+	cli.workDir, err = os.Getwd()
+	if err != nil {
+		return fmt.Errorf("unable to get current directory: %w", err)
+	}
 	if cli.chdir != "." {
-		origDir, err = os.Getwd()
-		if err != nil {
-			return fmt.Errorf("unable to get current directory: %w", err)
-		}
+		origDir = cli.workDir
 		err = os.Chdir(cli.chdir)
 		if err != nil {
 			return fmt.Errorf("unable to change directory to %s: %w", cli.chdir, err)
 		}
 	}
-	action := cmd.Name()
-	switch cmd.Name() {
-	case "check", "scan", "update":
-	default:
-		return fmt.Errorf("unhandled command %s", cmd.Name())
-	}
-
 	// loop over files
 	walk, err := filesearch.New(args, conf.Files)
 	if err != nil {
 		return err
 	}
-	changes := []*processor.Change{}
+	report := actionReport{
+		Action:  action,
+		Files:   []actionFile{},
+		Changes: []*processor.Change{},
+	}
+	errs := []error{}
+	// diffs are written to stdout to be used as a patch, moving other output to stderr
+	var diffOut io.Writer
+	reportOut := cmd.OutOrStdout()
+	if cli.diff {
+		diffOut = cmd.OutOrStdout()
+		reportOut = cmd.ErrOrStderr()
+	}
 	for {
 		filename, fileKey, err := walk.Next()
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
+			if !errors.Is(err, io.EOF) {
+				errs = append(errs, err)
 			}
-			return err
+			break
+		}
+		report.Files = append(report.Files, actionFile{Filename: filename, Config: fileKey})
+	}
+	// files with a lock source are processed after the files with the processors they reference
+	fileDepth := map[string]int{}
+	for key, f := range conf.Files {
+		for _, p := range f.Processors {
+			fileDepth[key] = max(fileDepth[key], processor.Depth(*conf, p))
 		}
-		fmt.Printf("processing file: %s for config %s\n", filename, fileKey)
-		curChanges, err := cli.procFile(ctx, filename, fileKey, conf, action, locks)
+	}
+	slices.SortStableFunc(report.Files, func(a, b actionFile) int {
+		return cmp.Compare(fileDepth[a.Config], fileDepth[b.Config])
+	})
+	for _, f := range report.Files {
+		filename, fileKey := f.Filename, f.Config
+		// progress is output as each file is processed, the json and yaml reports include the list of files
+		if cli.format == defaultFormat {
+			_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "processing file: %s for config %s\n", filename, fileKey)
+		}
+		// each file updates a copy of the locks, which is only kept when the file is successfully processed
+		fileLocks := locks.Clone()
+		curChanges, err := cli.procFile(ctx, filename, fileKey, conf, action, fileLocks, diffOut, procOpts...)
 		if err != nil {
-			return err
+			errs = append(errs, err)
+			continue
 		}
+		locks = fileLocks
 		if len(curChanges) > 0 {
-			changes = append(changes, curChanges...)
+			report.Changes = append(report.Changes, curChanges...)
 		}
 	}
-	// display changes
-	for _, change := range changes {
-		fmt.Printf("Version changed: filename=%s, processor=%s, key=%s, old=%s, new=%s\n",
-			change.Filename, change.Processor, change.Key, change.Orig, change.New)
+	// metadata loaded by templates is saved to the cache, failing to save does not change the results
+	_ = source.CacheFlush()
+	// a key that does not match any file is likely a typo, and is not added to the lock file
+	if action == "set" && len(errs) == 0 && !setMatched.Load() {
+		errs = append(errs, fmt.Errorf("key %s was not found for processor %s in any file", cli.key, cli.processors[0]))
+	}
+	for _, err := range errs {
+		report.Errors = append(report.Errors, err.Error())
 	}
+	// display the files and changes
+	outErr := cli.reportWrite(reportOut, report)
 
 	if origDir != "." {
 		err = os.Chdir(origDir)
@@ -208,27 +378,100 @@ func (cli *cliOpts) runAction(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("unable to change directory to %s: %w", origDir, err)
 		}
 	}
-	if !cli.dryrun {
-		switch action {
-		case "scan", "update":
-			err = cli.locksSave(locks, cli.prune)
-			if err != nil {
-				return err
-			}
-		case "check":
-			if len(changes) > 0 {
-				return fmt.Errorf("changes detected")
-			}
+	// locks are saved even when some files failed, to match the files that were updated
+	// unused entries are not pruned after a failure since the failed files did not mark their entries as used
+	if !cli.dryrun && slices.Contains([]string{"apply", "scan", "set", "update"}, action) && (action != "set" || setMatched.Load()) {
+		err = cli.locksSave(locks, cli.prune && len(errs) == 0)
+		if err != nil {
+			errs = append(errs, err)
 		}
 	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if outErr != nil {
+		return fmt.Errorf("failed to output results: %w", outErr)
+	}
+	if !cli.dryrun && action == "check" && len(report.Changes) > 0 {
+		return fmt.Errorf("changes detected")
+	}
 	return nil
 }
 
+// actionReport is the output from commands that process files.
+type actionReport struct {
+	Action  string              `yaml:"action" json:"action"`                     // name of the command
+	Files   []actionFile        `yaml:"files" json:"files"`                       // files processed
+	Changes []*processor.Change `yaml:"changes" json:"changes"`                   // changes found or made
+	Errors  []string            `yaml:"errors,omitempty" json:"errors,omitempty"` // errors encountered
+}
+
+// actionFile is a file processed by a command.
+type actionFile struct {
+	Filename string `yaml:"filename" json:"filename"` // name of the processed file
+	Config   string `yaml:"config" json:"config"`     // name of the file entry in the config
+}
+
+// MarshalPretty outputs each change on a line, the processed files are output as progress while running.
+func (r actionReport) MarshalPretty() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	for _, change := range r.Changes {
+		fmt.Fprintf(buf, "Version changed: filename=%s, processor=%s, key=%s, old=%s, new=%s\n",
+			change.Filename, change.Processor, change.Key, change.Orig, change.New)
+	}
+	return buf.Bytes(), nil
+}
+
+// reportWrite outputs the report using the requested format.
+func (cli *cliOpts) reportWrite(out io.Writer, report actionReport) error {
+	switch cli.format {
+	case formatJSON:
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	case formatYAML:
+		return yaml.NewEncoder(out).Encode(report)
+	default:
+		return template.Writer(out, cli.format, report)
+	}
+}
+
+func (cli *cliOpts) runCacheClear(cmd *cobra.Command, args []string) error {
+	if cli.cacheDir == "" {
+		cli.cacheDir = os.Getenv(envCache)
+	}
+	return source.CacheClear(cli.cacheDir)
+}
+
 func (cli *cliOpts) runVersion(cmd *cobra.Command, args []string) error {
 	info := version.GetInfo()
 	return template.Writer(cmd.OutOrStdout(), cli.format, info)
 }
 
+// cacheSetup configures the cache of source results, which is disabled when no directory is set.
+func (cli *cliOpts) cacheSetup() error {
+	if cli.cacheDir == "" {
+		if dir, ok := os.LookupEnv(envCache); ok {
+			cli.cacheDir = dir
+		}
+	}
+	opts := source.CacheOpts{
+		TTL:     cli.cacheTTL,
+		Refresh: cli.refresh,
+	}
+	if cli.cacheDir != "" {
+		// the path is resolved before changing directories
+		dir, err := filepath.Abs(cli.cacheDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve cache directory %s: %w", cli.cacheDir, err)
+		}
+		opts.Dir = dir
+	}
+	source.CacheSetup(opts)
+	return nil
+}
+
 func (cli *cliOpts) getConf() (*config.Config, error) {
 	// if conf not provided, attempt to use env
 	if cli.confFile == "" {
@@ -279,7 +522,7 @@ type procFileChan struct {
 	err     error
 }
 
-func (cli *cliOpts) procFile(ctx context.Context, filename string, fileKey string, conf *config.Config, action string, locks *lockfile.Locks) ([]*processor.Change, error) {
+func (cli *cliOpts) procFile(ctx context.Context, filename string, fileKey string, conf *config.Config, action string, locks *lockfile.Locks, diffOut io.Writer, procOpts ...processor.Opt) ([]*processor.Change, error) {
 	// TODO: for large files, write to a tmp file instead of using an in-memory buffer
 	//#nosec G304 file to read is controlled by user running the command
 	origBytes, err := os.ReadFile(filename)
@@ -291,7 +534,12 @@ func (cli *cliOpts) procFile(ctx context.Context, filename string, fileKey strin
 	rdr = io.NopCloser(bRdr)
 	procCount := 0
 	procResult := make(chan procFileChan)
-	for _, p := range conf.Files[fileKey].Processors {
+	// each processor reads the output of the previous processor, so a processor with a lock source runs after the processor it references
+	procNames := slices.Clone(conf.Files[fileKey].Processors)
+	slices.SortStableFunc(procNames, func(a, b string) int {
+		return cmp.Compare(processor.Depth(*conf, a), processor.Depth(*conf, b))
+	})
+	for _, p := range procNames {
 		// skip scans when CLI arg requests specific scans
 		if len(cli.processors) > 0 && !slices.Contains(cli.processors, p) {
 			continue
@@ -303,7 +551,7 @@ func (cli *cliOpts) procFile(ctx context.Context, filename string, fileKey strin
 		pr, pw := io.Pipe()
 		// run processor in goroutine to read and write detected changes
 		go func(procName string, r io.ReadCloser, w io.WriteCloser) {
-			changes, pErr := processor.Process(ctx, *conf, procName, filename, r, w, locks)
+			changes, pErr := processor.Process(ctx, *conf, procName, filename, r, w, locks, procOpts...)
 			rErr := r.Close()
 			wErr := w.Close()
 			var err error
@@ -350,8 +598,23 @@ func (cli *cliOpts) procFile(ctx context.Context, filename string, fileKey strin
 	if len(errs) > 0 {
 		return changes, errors.Join(errs...)
 	}
+	if diffOut != nil {
+		// paths in the diff are relative to the directory the command was run from, to use with "git apply"
+		name := filename
+		if abs, err := filepath.Abs(filename); err == nil && cli.workDir != "" {
+			if rel, err := filepath.Rel(cli.workDir, abs); err == nil {
+				name = rel
+			}
+		}
+		name = filepath.ToSlash(name)
+		if d := diff.Unified("a/"+name, "b/"+name, origBytes, finalBytes); d != nil {
+			if _, err := diffOut.Write(d); err != nil {
+				return nil, fmt.Errorf("failed to output diff for %s: %w", filename, err)
+			}
+		}
+	}
 	// if the file was changed and updates are being performed, output to a tmpfile and then copy/replace orig file
-	if !cli.dryrun && action == "update" && !bytes.Equal(origBytes, finalBytes) {
+	if !cli.dryrun && slices.Contains([]string{"apply", "reset", "set", "update"}, action) && !bytes.Equal(origBytes, finalBytes) {
 		dir := filepath.Dir(filename)
 		tmp, err := os.CreateTemp(dir, filepath.Base(filename))
 		if err != nil {
@@ -19,12 +19,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/sudo-bmitch/version-bump/internal/lockfile"
 )
 
 type cobraTestOpts struct {
-	stdin io.Reader
+	stdin  io.Reader
+	stderr io.Writer
 }
 
 func cobraTest(t *testing.T, opts *cobraTestOpts, args ...string) (string, error) {
@@ -37,6 +43,9 @@ func cobraTest(t *testing.T, opts *cobraTestOpts, args ...string) (string, error
 	}
 	rootCmd.SetOut(buf)
 	rootCmd.SetErr(buf)
+	if opts != nil && opts.stderr != nil {
+		rootCmd.SetErr(opts.stderr)
+	}
 	rootCmd.SetArgs(args)
 
 	err := rootCmd.Execute()
@@ -59,8 +68,37 @@ func TestRootCmd(t *testing.T) {
 			outContains: true,
 		},
 		{
-			name: "Check-Good",
-			args: []string{"check", "--conf", "./testdata/root-conf.yaml", "root-good.txt"},
+			name:      "Check-Good",
+			args:      []string{"check", "--conf", "./testdata/root-conf.yaml", "root-good.txt"},
+			expectOut: "processing file: root-good.txt for config root-*.txt",
+		},
+		{
+			name: "Check-Good-JSON",
+			args: []string{"check", "--conf", "./testdata/root-conf.yaml", "--format", "json", "root-good.txt"},
+			expectOut: `{
+  "action": "check",
+  "files": [
+    {
+      "filename": "root-good.txt",
+      "config": "root-*.txt"
+    }
+  ],
+  "changes": []
+}`,
+		},
+		{
+			name: "Check-Good-YAML",
+			args: []string{"check", "--conf", "./testdata/root-conf.yaml", "--format", "yaml", "root-good.txt"},
+			expectOut: `action: check
+files:
+- filename: root-good.txt
+  config: root-*.txt
+changes: []`,
+		},
+		{
+			name:      "Check-Good-Template",
+			args:      []string{"check", "--conf", "./testdata/root-conf.yaml", "--format", "{{ len .Files }}", "root-good.txt"},
+			expectOut: "1",
 		},
 		{
 			name:      "Check-Bad",
@@ -68,8 +106,9 @@ func TestRootCmd(t *testing.T) {
 			expectErr: fmt.Errorf("changes detected"),
 		},
 		{
-			name: "Check-Old-Good",
-			args: []string{"check", "--conf", "./testdata/root-conf-old.yaml", "root-good.txt"},
+			name:      "Check-Old-Good",
+			args:      []string{"check", "--conf", "./testdata/root-conf-old.yaml", "root-good.txt"},
+			expectOut: "processing file: root-good.txt for config root-*.txt",
 		},
 		{
 			name:      "Check-Old-Bad",
@@ -97,3 +136,440 @@ func TestRootCmd(t *testing.T) {
 		})
 	}
 }
+
+func TestRootProgress(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	out, err := cobraTest(t, &cobraTestOpts{stderr: stderr}, "check", "--conf", "./testdata/root-conf.yaml", "root-good.txt")
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	if out != "" {
+		t.Errorf("unexpected output: %s", out)
+	}
+	if stderr.String() != "processing file: root-good.txt for config root-*.txt\n" {
+		t.Errorf("unexpected progress: %s", stderr.String())
+	}
+}
+
+func TestRootScan(t *testing.T) {
+	dir := t.TempDir()
+	testdataCopy(t, dir, "root-conf.yaml", "root-conf.lock", "root-bad.txt")
+	confFile := filepath.Join(dir, "root-conf.yaml")
+	lockFile := filepath.Join(dir, "root-conf.lock")
+	_, err := cobraTest(t, nil, "scan", "--conf", confFile, "root-bad.txt")
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	// the version from the file should be in the lock, the manual source is not used
+	locks, err := lockfile.LoadFile(lockFile)
+	if err != nil {
+		t.Fatalf("failed to load lock file: %v", err)
+	}
+	l, err := locks.Get("root-manual", "root-manual-ver")
+	if err != nil {
+		t.Fatalf("failed to get lock: %v", err)
+	}
+	if l.Version != "bad" {
+		t.Errorf("unexpected version in lock, expected bad, received %s", l.Version)
+	}
+	// the scanned file should not be modified
+	b, err := os.ReadFile(filepath.Join(dir, "root-bad.txt"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(b) != "manual-ver=bad\n" {
+		t.Errorf("scanned file was modified: %s", string(b))
+	}
+}
+
+func TestRootApply(t *testing.T) {
+	dir := t.TempDir()
+	testdataCopy(t, dir, "root-conf.yaml", "root-good.txt")
+	confFile := filepath.Join(dir, "root-conf.yaml")
+	lockFile := filepath.Join(dir, "root-conf.lock")
+	err := os.WriteFile(lockFile, []byte(`{"name":"root-manual","key":"root-manual-ver","version":"locked"}`+"\n"), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write lock file: %v", err)
+	}
+	// check against the source succeeds, but the file does not match the lock
+	_, err = cobraTest(t, nil, "check", "--conf", confFile, "root-good.txt")
+	if err != nil {
+		t.Fatalf("check failed: %v", err)
+	}
+	_, err = cobraTest(t, nil, "check", "--locked", "--conf", confFile, "root-good.txt")
+	if err == nil || err.Error() != "changes detected" {
+		t.Fatalf("check with lock did not detect changes: %v", err)
+	}
+	// apply the version from the lock
+	_, err = cobraTest(t, nil, "apply", "--conf", confFile, "root-good.txt")
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "root-good.txt"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(b) != "manual-ver=locked\n" {
+		t.Errorf("lock was not applied to file: %s", string(b))
+	}
+	_, err = cobraTest(t, nil, "check", "--locked", "--conf", confFile, "root-good.txt")
+	if err != nil {
+		t.Errorf("check with lock failed after apply: %v", err)
+	}
+}
+
+func TestRootSet(t *testing.T) {
+	dir := t.TempDir()
+	testdataCopy(t, dir, "root-conf.yaml", "root-conf.lock", "root-good.txt")
+	confFile := filepath.Join(dir, "root-conf.yaml")
+	lockFile := filepath.Join(dir, "root-conf.lock")
+	_, err := cobraTest(t, nil, "set", "--conf", confFile, "--processor", "root-manual", "--key", "root-manual-ver", "pinned")
+	if err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "root-good.txt"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(b) != "manual-ver=pinned\n" {
+		t.Errorf("version was not set in file: %s", string(b))
+	}
+	locks, err := lockfile.LoadFile(lockFile)
+	if err != nil {
+		t.Fatalf("failed to load lock file: %v", err)
+	}
+	l, err := locks.Get("root-manual", "root-manual-ver")
+	if err != nil {
+		t.Fatalf("failed to get lock: %v", err)
+	}
+	if l.Version != "pinned" {
+		t.Errorf("unexpected version in lock, expected pinned, received %s", l.Version)
+	}
+	// a key that is not found in any file is not added to the lock
+	_, err = cobraTest(t, nil, "set", "--conf", confFile, "--processor", "root-manual", "--key", "root-manual-typo", "pinned")
+	if err == nil {
+		t.Errorf("set with an unknown key did not fail")
+	}
+	locks, err = lockfile.LoadFile(lockFile)
+	if err != nil {
+		t.Fatalf("failed to load lock file: %v", err)
+	}
+	if _, err := locks.Get("root-manual", "root-manual-typo"); err == nil {
+		t.Errorf("lock was added for an unknown key")
+	}
+	// an unknown processor is rejected
+	_, err = cobraTest(t, nil, "set", "--conf", confFile, "--processor", "missing", "--key", "root-manual-ver", "pinned")
+	if err == nil || err.Error() != "processor not defined: missing" {
+		t.Errorf("unexpected error for missing processor: %v", err)
+	}
+}
+
+func TestRootReset(t *testing.T) {
+	dir := t.TempDir()
+	testdataCopy(t, dir, "root-conf.yaml", "root-bad.txt")
+	confFile := filepath.Join(dir, "root-conf.yaml")
+	lockFile := filepath.Join(dir, "root-conf.lock")
+	lockOrig := []byte(`{"name":"root-manual","key":"root-manual-ver","version":"locked"}` + "\n")
+	err := os.WriteFile(lockFile, lockOrig, 0o644)
+	if err != nil {
+		t.Fatalf("failed to write lock file: %v", err)
+	}
+	_, err = cobraTest(t, nil, "reset", "--conf", confFile, "root-bad.txt")
+	if err != nil {
+		t.Fatalf("reset failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "root-bad.txt"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(b) != "manual-ver=locked\n" {
+		t.Errorf("file was not reset: %s", string(b))
+	}
+	lockAfter, err := os.ReadFile(lockFile)
+	if err != nil {
+		t.Fatalf("failed to read lock file: %v", err)
+	}
+	if !bytes.Equal(lockOrig, lockAfter) {
+		t.Errorf("lock file was modified: %s", string(lockAfter))
+	}
+}
+
+func TestRootUpdateError(t *testing.T) {
+	dir := t.TempDir()
+	confFile := filepath.Join(dir, "conf.yaml")
+	lockFile := filepath.Join(dir, "conf.lock")
+	files := map[string]string{
+		"conf.yaml": `
+files:
+  "a-*.txt":
+    processors: ["ok"]
+  "b-*.txt":
+    processors: ["ok", "fail"]
+processors:
+  "ok":
+    key: "{{ .ScanMatch.Name }}"
+    scan: "regexp"
+    scanArgs:
+      regexp: '^(?P<Name>\w+)-ver=(?P<Version>\S+)$'
+    source: "manual"
+    sourceArgs:
+      Version: "new"
+  "fail":
+    key: "fail"
+    scan: "regexp"
+    scanArgs:
+      regexp: '^fail=(?P<Version>\S+)$'
+    source: "fail"
+scans:
+  "regexp":
+    type: "regexp"
+sources:
+  "manual":
+    type: "manual"
+  "fail":
+    type: "custom"
+    args:
+      cmd: "exit 1"
+`,
+		"conf.lock":  `{"name":"ok","key":"b","version":"old"}` + "\n" + `{"name":"other","key":"x","version":"1"}` + "\n",
+		"a-ok.txt":   "a-ver=old\n",
+		"b-fail.txt": "b-ver=old\nfail=1\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	_, err := cobraTest(t, nil, "update", "--conf", confFile, "b-fail.txt", "a-ok.txt")
+	if err == nil {
+		t.Fatalf("update did not fail")
+	}
+	// the successful file is updated, and the failed file is unchanged
+	for name, expect := range map[string]string{"a-ok.txt": "a-ver=new\n", "b-fail.txt": files["b-fail.txt"]} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(b) != expect {
+			t.Errorf("unexpected content in %s: %s", name, string(b))
+		}
+	}
+	// the lock matches the files, including the successful file and excluding changes from the failed file
+	locks, err := lockfile.LoadFile(lockFile)
+	if err != nil {
+		t.Fatalf("failed to load lock file: %v", err)
+	}
+	for _, expect := range []lockfile.Lock{
+		{Name: "ok", Key: "a", Version: "new"},
+		{Name: "ok", Key: "b", Version: "old"},
+		{Name: "other", Key: "x", Version: "1"},
+	} {
+		l, err := locks.Get(expect.Name, expect.Key)
+		if err != nil {
+			t.Errorf("failed to get lock %s/%s: %v", expect.Name, expect.Key, err)
+			continue
+		}
+		if l.Version != expect.Version {
+			t.Errorf("unexpected version for %s/%s, expected %s, received %s", expect.Name, expect.Key, expect.Version, l.Version)
+		}
+	}
+}
+
+func TestRootLockSource(t *testing.T) {
+	dir := t.TempDir()
+	confFile := filepath.Join(dir, "conf.yaml")
+	files := map[string]string{
+		"conf.yaml": `
+files:
+  "a-docker.txt":
+    processors: ["docker"]
+  "b-make.txt":
+    processors: ["upstream"]
+  "c-both.txt":
+    processors: ["docker", "upstream"]
+processors:
+  "upstream":
+    key: "app"
+    scan: "regexp"
+    scanArgs:
+      regexp: '^VER=(?P<Version>\S+)$'
+    source: "manual"
+    sourceArgs:
+      Version: "1.2.3"
+  "docker":
+    key: "app"
+    scan: "regexp"
+    scanArgs:
+      regexp: '^ARG VER=(?P<Version>\S+)$'
+    source: "lock"
+    sourceArgs:
+      processor: "upstream"
+    template: "v{{ .Version }}"
+scans:
+  "regexp":
+    type: "regexp"
+sources:
+  "manual":
+    type: "manual"
+  "lock":
+    type: "lock"
+`,
+		"conf.lock":    `{"name":"upstream","key":"app","version":"1.1.0"}` + "\n",
+		"a-docker.txt": "ARG VER=v1.0.0\n",
+		"b-make.txt":   "VER=1.0.0\n",
+		"c-both.txt":   "ARG VER=v1.0.0\nVER=1.0.0\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	// without the upstream processor, the version from the lock file is used with a warning
+	stderr := &bytes.Buffer{}
+	_, err := cobraTest(t, &cobraTestOpts{stderr: stderr}, "update", "--conf", confFile, "--processor", "docker", "a-docker.txt")
+	if err != nil {
+		t.Errorf("update without the upstream processor failed: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "a-docker.txt")); err != nil || string(b) != "ARG VER=v1.1.0\n" {
+		t.Errorf("unexpected content in a-docker.txt: %s, %v", string(b), err)
+	}
+	if !strings.Contains(stderr.String(), "warning: processor upstream has not resolved key app") {
+		t.Errorf("missing warning for the lock file version: %s", stderr.String())
+	}
+	// the upstream processor runs first, even when the files and processors are listed in the other order
+	_, err = cobraTest(t, nil, "update", "--conf", confFile, "a-docker.txt", "c-both.txt", "b-make.txt")
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	for name, expect := range map[string]string{
+		"a-docker.txt": "ARG VER=v1.2.3\n",
+		"b-make.txt":   "VER=1.2.3\n",
+		"c-both.txt":   "ARG VER=v1.2.3\nVER=1.2.3\n",
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if string(b) != expect {
+			t.Errorf("unexpected content in %s: %s", name, string(b))
+		}
+	}
+}
+
+func TestRootDiff(t *testing.T) {
+	dir := t.TempDir()
+	testdataCopy(t, dir, "root-conf.yaml", "root-conf.lock", "root-bad.txt")
+	confFile := filepath.Join(dir, "root-conf.yaml")
+	// diff paths are relative to the current directory
+	t.Chdir(dir)
+	stderr := &bytes.Buffer{}
+	out, err := cobraTest(t, &cobraTestOpts{stderr: stderr}, "update", "--conf", confFile, "--dry-run", "--diff", "root-bad.txt")
+	if err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	expect := `--- a/root-bad.txt
++++ b/root-bad.txt
+@@ -1 +1 @@
+-manual-ver=bad
++manual-ver=good`
+	if out != expect {
+		t.Errorf("unexpected diff, expected:\n%s\nreceived:\n%s", expect, out)
+	}
+	if !strings.Contains(stderr.String(), "Version changed: filename=root-bad.txt") {
+		t.Errorf("changes not reported to stderr: %s", stderr.String())
+	}
+	// dry run does not modify the file
+	b, err := os.ReadFile(filepath.Join(dir, "root-bad.txt"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(b) != "manual-ver=bad\n" {
+		t.Errorf("file was modified: %s", string(b))
+	}
+}
+
+func TestRootDiffWorkDir(t *testing.T) {
+	dir := t.TempDir()
+	confDir := filepath.Join(dir, "conf")
+	otherDir := filepath.Join(dir, "other")
+	for _, d := range []string{confDir, otherDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatalf("failed to create %s: %v", d, err)
+		}
+	}
+	testdataCopy(t, confDir, "root-conf.yaml", "root-conf.lock", "root-bad.txt")
+	tests := []struct {
+		name    string
+		workDir string
+		conf    string
+		expName string
+	}{
+		{
+			name:    "parent",
+			workDir: dir,
+			conf:    filepath.Join("conf", "root-conf.yaml"),
+			expName: "conf/root-bad.txt",
+		},
+		{
+			name:    "sibling",
+			workDir: otherDir,
+			conf:    filepath.Join("..", "conf", "root-conf.yaml"),
+			expName: "../conf/root-bad.txt",
+		},
+		{
+			name:    "chdir",
+			workDir: confDir,
+			conf:    "root-conf.yaml",
+			expName: "root-bad.txt",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Chdir(tc.workDir)
+			stderr := &bytes.Buffer{}
+			out, err := cobraTest(t, &cobraTestOpts{stderr: stderr}, "update", "--conf", tc.conf, "--dry-run", "--diff", "root-bad.txt")
+			if err != nil {
+				t.Fatalf("update failed: %v", err)
+			}
+			expect := "--- a/" + tc.expName + "\n+++ b/" + tc.expName + "\n"
+			if !strings.HasPrefix(out, expect) {
+				t.Errorf("unexpected diff, expected prefix:\n%s\nreceived:\n%s", expect, out)
+			}
+		})
+	}
+}
+
+func TestRootCacheClear(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"git-1234.cache", "other.txt"} {
+		err := os.WriteFile(filepath.Join(dir, name), []byte("test"), 0o644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	_, err := cobraTest(t, nil, "cache", "clear", "--cache-dir", dir)
+	if err != nil {
+		t.Fatalf("cache clear failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "git-1234.cache")); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("cache file was not deleted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.txt")); err != nil {
+		t.Errorf("other file was deleted: %v", err)
+	}
+}
+
+// testdataCopy copies files from the testdata directory into dir.
+func testdataCopy(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		b, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		err = os.WriteFile(filepath.Join(dir, file), b, 0o644)
+		if err != nil {
+			t.Fatalf("failed to write %s: %v", file, err)
+		}
+	}
+}